@@ -3,43 +3,90 @@ package environment
 import (
 	"bufio"
 	"bytes"
+	"database/sql"
+	"encoding"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"log"
+	"math"
+	"net"
 	"os"
 	"path/filepath"
 	"reflect"
 	"regexp"
+	"runtime"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 )
 
 var (
 	envVarRegex      = regexp.MustCompile(`\${([a-zA-Z_][a-zA-Z0-9_]*)}`)
 	validEnvVarRegex = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+	defaultVarRegex  = regexp.MustCompile(`\$(?:{[a-zA-Z_][a-zA-Z0-9_]*}|[a-zA-Z_][a-zA-Z0-9_]*)`)
+
+	durationType   = reflect.TypeOf(time.Duration(0))
+	rawMessageType = reflect.TypeOf(json.RawMessage(nil))
+	ipNetType      = reflect.TypeOf(net.IPNet{})
+	timeType       = reflect.TypeOf(time.Time{})
+
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
 )
 
-func fillSpecification[T any](instance *T, paths ...string) error {
-	envVars := make(map[string]string, len(paths))
+func fillSpecification(instance any, opts Options, paths ...string) error {
+	opts.origins = make(map[string]string)
+	envPaths := make([]string, 0, len(paths))
 	for _, path := range paths {
-		fileVars, err := loadEnv(path)
+		if filepath.Ext(path) != ".toml" {
+			envPaths = append(envPaths, path)
+			continue
+		}
+		vars, err := loadTOML(path)
 		if err != nil {
-			return fmt.Errorf("error loading .env file: %v", err)
+			return fmt.Errorf("error loading TOML file: %v", err)
 		}
-		for k, v := range fileVars {
-			envVars[k] = v
+		if opts.base == nil {
+			opts.base = make(map[string]string, len(vars))
+		}
+		for k, v := range vars {
+			opts.base[k] = v
+			opts.origins[k] = path
 		}
 	}
 
-	if err := parseEnv(instance, envVars); err != nil {
+	opts.rawValues = make(map[string]string)
+	envVars, err := loadFiles(opts, envPaths...)
+	if err != nil {
+		return err
+	}
+
+	if err := parseEnv(instance, envVars, opts); err != nil {
 		return fmt.Errorf("field load environment: %v", err)
 	}
 	return nil
 }
 
-func loadEnv(filename string) (map[string]string, error) {
+// loadFiles loads paths in order, later files overriding earlier ones.
+func loadFiles(opts Options, paths ...string) (map[string]string, error) {
+	envVars := make(map[string]string, len(paths))
+	for _, path := range paths {
+		fileVars, err := loadEnv(path, opts)
+		if err != nil {
+			return nil, fmt.Errorf("error loading .env file: %v", err)
+		}
+		for k, v := range fileVars {
+			envVars[k] = v
+		}
+	}
+	return envVars, nil
+}
+
+func loadEnv(filename string, opts Options) (map[string]string, error) {
 	file, err := os.Open(filepath.Clean(filename))
 	if err != nil {
 		if errors.Is(err, os.ErrNotExist) {
@@ -53,18 +100,128 @@ func loadEnv(filename string) (map[string]string, error) {
 		}
 	}(file)
 
+	return parseReader(filename, file, opts)
+}
+
+// parseReader parses env-formatted input; name identifies the source in
+// warnings and errors.
+func parseReader(name string, r io.Reader, opts Options) (map[string]string, error) {
 	envVars := make(map[string]string)
-	scanner := bufio.NewScanner(file)
+	if err := scanEnv(name, r, opts, envVars, nil); err != nil {
+		return nil, err
+	}
+	return envVars, nil
+}
+
+// includeDirective loads another file at its position, so its variables
+// override earlier lines and are overridden by later ones. The path must be
+// double-quoted, so ordinary comments that start with the word are left
+// alone.
+const includeDirective = "#include "
+
+// includeTarget returns the quoted path of an include directive line.
+func includeTarget(line string) (string, bool) {
+	rest, ok := strings.CutPrefix(line, includeDirective)
+	if !ok {
+		return "", false
+	}
+	rest = strings.TrimSpace(rest)
+	if len(rest) < 2 || rest[0] != '"' || rest[len(rest)-1] != '"' || strings.Contains(rest[1:len(rest)-1], `"`) {
+		return "", false
+	}
+	return rest[1 : len(rest)-1], true
+}
+
+// includePath resolves an include target relative to the including file.
+func includePath(name, target string) string {
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(filepath.Dir(name), target)
+	}
+	return filepath.Clean(target)
+}
+
+// heredocQuote opens a value spanning several lines when it follows the =
+// on its own, and closes it on a line of its own.
+const heredocQuote = `"""`
+
+// scanEnv parses env-formatted input line by line, storing each resolved
+// value in envVars, where later ${VAR} references can see it, and passing
+// it to fn when fn is not nil. Scanning stops at the first error from fn.
+func scanEnv(name string, r io.Reader, opts Options, envVars map[string]string, fn func(key, value string) error) error {
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(r)
 	var buffer bytes.Buffer
 	var multiline bool
+	var section string
+	var heredocKey string
+	var heredoc []string
+
+	store := func(key, value string, verbatim bool) error {
+		if seen[key] {
+			switch opts.DuplicateKeys {
+			case DuplicateKeysWarn:
+				opts.warn(key, "duplicate key %s in %s", key, name)
+			case DuplicateKeysError:
+				return fmt.Errorf("duplicate key %s in %s", key, name)
+			}
+		}
+
+		if opts.rawValues != nil {
+			opts.rawValues[key] = value
+		}
+		if opts.origins != nil {
+			opts.origins[key] = name
+		}
+		if !verbatim && !opts.RawValues {
+			processed, err := processValue(value, opts)
+			if err != nil {
+				return fmt.Errorf("invalid value for %s: %v", key, err)
+			}
+			if opts.StrictExpansion {
+				if missing := unresolvedVar(processed, envVars, !opts.NoOSExpansion); missing != "" {
+					return fmt.Errorf("unresolved variable %s in %s", missing, key)
+				}
+			}
+			value = expandEnvVars(processed, envVars, !opts.NoOSExpansion)
+		}
+		envVars[key] = value
+		seen[key] = true
+
+		if fn != nil {
+			return fn(key, value)
+		}
+		return nil
+	}
 
 	for scanner.Scan() {
+		if heredoc != nil {
+			// Heredoc lines are kept verbatim until a closing """ line.
+			if strings.TrimSpace(scanner.Text()) != heredocQuote {
+				heredoc = append(heredoc, scanner.Text())
+				continue
+			}
+			if err := store(heredocKey, strings.Join(heredoc, "\n"), true); err != nil {
+				return err
+			}
+			heredoc = nil
+			continue
+		}
+
 		line := strings.TrimSpace(scanner.Text())
-		if line == "" || strings.HasPrefix(line, "#") {
+		if target, ok := includeTarget(line); ok {
+			if opts.including == nil {
+				opts.including = map[string]bool{filepath.Clean(name): true}
+			}
+			if err := includeEnv(name, target, opts, envVars, fn); err != nil {
+				return err
+			}
+			continue
+		}
+		if line == "" || strings.HasPrefix(line, "#") || (opts.SemicolonComments && strings.HasPrefix(line, ";")) {
 			continue
 		}
 
-		if strings.HasSuffix(line, "\\") {
+		if !opts.NoLineContinuation && isContinuation(buffer.String(), line) {
 			multiline = true
 			buffer.WriteString(strings.TrimSuffix(line, "\\"))
 			continue
@@ -77,136 +234,904 @@ func loadEnv(filename string) (map[string]string, error) {
 			multiline = false
 		}
 
+		if opts.Sections && strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = sectionPrefix(line[1 : len(line)-1])
+			continue
+		}
+
 		parts := strings.SplitN(line, "=", 2)
 		if len(parts) != 2 {
 			continue
 		}
 
-		key := strings.TrimSpace(parts[0])
-		if !validEnvVarRegex.MatchString(key) {
-			return nil, fmt.Errorf("invalid environment variable name: %s", key)
+		key := section + strings.TrimSpace(parts[0])
+		if !opts.keyPattern().MatchString(key) {
+			return fmt.Errorf("invalid environment variable name: %s", key)
 		}
 
-		value := processValue(strings.TrimSpace(parts[1]))
-		value = expandEnvVars(value, envVars)
-		envVars[key] = value
+		if strings.TrimSpace(parts[1]) == heredocQuote {
+			heredocKey, heredoc = key, []string{}
+			continue
+		}
+		if err := store(key, parts[1], false); err != nil {
+			return err
+		}
 	}
 
-	if err := scanner.Err(); err != nil {
-		return nil, err
+	if heredoc != nil {
+		return fmt.Errorf("unterminated heredoc for %s in %s", heredocKey, name)
 	}
+	return scanner.Err()
+}
 
-	return envVars, nil
+// includeEnv scans the file target, relative to the directory of the
+// including file name, into envVars. opts.including holds the chain of
+// files being included, so a file that includes itself again is an error.
+func includeEnv(name, target string, opts Options, envVars map[string]string, fn func(key, value string) error) error {
+	target = includePath(name, target)
+	if opts.including[target] {
+		return fmt.Errorf("include cycle: %s includes %s", name, target)
+	}
+
+	file, err := os.Open(target)
+	if err != nil {
+		return fmt.Errorf("include in %s: %v", name, err)
+	}
+	defer func(file *os.File) {
+		if err := file.Close(); err != nil {
+			log.Fatalf("failed to close env file: %v", err)
+		}
+	}(file)
+
+	opts.including[target] = true
+	defer delete(opts.including, target)
+	return scanEnv(target, file, opts, envVars, fn)
 }
 
-func processValue(value string) string {
+// isContinuation reports whether line, following the already joined
+// pending text, ends in a backslash that continues onto the next line. The
+// backslash must not itself be escaped and must either follow whitespace or
+// sit inside an unterminated quoted value, so DIR=C:\logs\ reads as a path.
+func isContinuation(pending, line string) bool {
+	trimmed := strings.TrimRight(line, `\`)
+	if (len(line)-len(trimmed))%2 == 0 {
+		return false
+	}
+	if trimmed == "" || strings.HasSuffix(trimmed, " ") || strings.HasSuffix(trimmed, "\t") {
+		return true
+	}
+	_, value, _ := strings.Cut(pending+trimmed, "=")
+	return hasOpenQuote(value)
+}
+
+// hasOpenQuote reports whether value starts a quoted string that is not
+// closed yet.
+func hasOpenQuote(value string) bool {
+	value = strings.TrimLeft(value, " \t")
+	if value == "" || (value[0] != '"' && value[0] != '\'') {
+		return false
+	}
+	quote := value[0]
+	for i := 1; i < len(value); i++ {
+		switch {
+		case value[i] == '\\' && quote == '"':
+			i++
+		case value[i] == quote:
+			return false
+		}
+	}
+	return true
+}
+
+// sectionPrefix turns a [db] header name into the DB_ key prefix; an empty
+// header returns to unprefixed keys.
+func sectionPrefix(name string) string {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return ""
+	}
+	return strings.ToUpper(name) + "_"
+}
+
+// processValue trims the whitespace around value, then strips matching
+// quotes, so that whitespace inside the quotes is preserved verbatim.
+func processValue(value string, opts Options) (string, error) {
+	value = strings.TrimSpace(stripInlineComment(value, opts.commentMarkers()))
 	if value == "" {
+		return value, nil
+	}
+
+	return decodeEscapes(unquote(value))
+}
+
+// unquote strips the quotes around value when a single quoted string spans
+// all of it, leaving lists such as "a","b" intact.
+func unquote(value string) string {
+	if len(value) < 2 {
+		return value
+	}
+	quote := value[0]
+	if quote != '"' && quote != '\'' {
 		return value
 	}
+	for i := 1; i < len(value); i++ {
+		switch {
+		case value[i] == '\\' && quote == '"':
+			i++
+		case value[i] == quote:
+			if i == len(value)-1 {
+				return value[1:i]
+			}
+			return value
+		}
+	}
+	return value
+}
+
+// decodeEscapes expands \n, \t, \r, \\, \uXXXX and \xHH. Other backslash
+// sequences are kept as written.
+func decodeEscapes(value string) (string, error) {
+	if !strings.Contains(value, `\`) {
+		return value, nil
+	}
 
-	value = strings.NewReplacer(
-		`\n`, "\n",
-		`\t`, "\t",
-		`\r`, "\r",
-		`\\`, `\`,
-	).Replace(value)
+	var sb strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] != '\\' || i+1 == len(value) {
+			sb.WriteByte(value[i])
+			continue
+		}
 
-	if quote := value[0]; (quote == '"' || quote == '\'') && value[len(value)-1] == quote {
-		value = value[1 : len(value)-1]
+		switch value[i+1] {
+		case 'n':
+			sb.WriteByte('\n')
+		case 't':
+			sb.WriteByte('\t')
+		case 'r':
+			sb.WriteByte('\r')
+		case '\\':
+			sb.WriteByte('\\')
+		case 'u', 'x':
+			digits := 4
+			if value[i+1] == 'x' {
+				digits = 2
+			}
+			end := i + 2 + digits
+			if end > len(value) {
+				return "", fmt.Errorf("malformed escape %s", value[i:])
+			}
+			code, err := strconv.ParseUint(value[i+2:end], 16, 32)
+			if err != nil {
+				return "", fmt.Errorf("malformed escape %s", value[i:end])
+			}
+			if !utf8.ValidRune(rune(code)) {
+				return "", fmt.Errorf("invalid code point in escape %s", value[i:end])
+			}
+			sb.WriteRune(rune(code))
+			i = end - 1
+			continue
+		default:
+			sb.WriteByte('\\')
+			sb.WriteByte(value[i+1])
+		}
+		i++
 	}
+	return sb.String(), nil
+}
 
+// stripInlineComment cuts value at the first comment marker (normally just
+// #) that is preceded by whitespace. Quotes only matter when the value
+// starts with one, in which case markers inside it are kept, so both
+// `"#ff0000" # red` and `value#tag` keep their hash while the apostrophe in
+// `don't panic # note` does not hide the comment.
+func stripInlineComment(value, markers string) string {
+	start := 0
+	trimmed := strings.TrimLeft(value, " \t")
+	if trimmed != "" && (trimmed[0] == '"' || trimmed[0] == '\'') {
+		// An unterminated quote keeps the whole value.
+		start = len(value)
+		offset := len(value) - len(trimmed)
+		for i := 1; i < len(trimmed); i++ {
+			if trimmed[i] == '\\' && trimmed[0] == '"' {
+				i++
+				continue
+			}
+			if trimmed[i] == trimmed[0] {
+				start = offset + i + 1
+				break
+			}
+		}
+	}
+
+	for i := start; i < len(value); i++ {
+		if strings.IndexByte(markers, value[i]) >= 0 && i > 0 && (value[i-1] == ' ' || value[i-1] == '\t') {
+			return value[:i]
+		}
+	}
 	return value
 }
 
-func expandEnvVars(value string, envVars map[string]string) string {
+func expandEnvVars(value string, envVars map[string]string, lookupOS bool) string {
 	return envVarRegex.ReplaceAllStringFunc(value, func(match string) string {
-		varName := match[2 : len(match)-1]
-		if val, exists := envVars[varName]; exists {
-			return val
-		}
-		if val, exists := os.LookupEnv(varName); exists {
+		if val, exists := resolveVar(match[2:len(match)-1], envVars, lookupOS); exists {
 			return val
 		}
 		return match
 	})
 }
 
-func parseEnv(cfg interface{}, envVars map[string]string) error {
-	val := reflect.ValueOf(cfg).Elem()
+// unresolvedVar returns the first ${VAR} reference in value that cannot be
+// resolved.
+func unresolvedVar(value string, envVars map[string]string, lookupOS bool) string {
+	for _, match := range envVarRegex.FindAllStringSubmatch(value, -1) {
+		if _, exists := resolveVar(match[1], envVars, lookupOS); !exists {
+			return match[1]
+		}
+	}
+	return ""
+}
+
+// resolveVar looks name up in envVars and, when lookupOS is set, in the
+// process environment.
+func resolveVar(name string, envVars map[string]string, lookupOS bool) (string, bool) {
+	if val, exists := envVars[name]; exists {
+		return val, true
+	}
+	if lookupOS {
+		return os.LookupEnv(name)
+	}
+	return "", false
+}
+
+// catchAllTag marks a map[string]string field that receives every parsed
+// variable.
+const catchAllTag = "*"
+
+// valueSource records where the value of a field came from.
+type valueSource int
+
+const (
+	sourceUnset valueSource = iota
+	sourceEnv
+	sourceDefault
+)
+
+type binder struct {
+	opts    Options
+	envVars map[string]string
+	mode    string
+	groups  map[string]*fieldGroup
+	used    map[string]bool
+
+	catchAll []reflect.Value
+	refs     []fieldRef
+	bound    []boundField
+}
+
+func parseEnv(cfg interface{}, envVars map[string]string, opts Options) error {
+	ptr := reflect.ValueOf(cfg)
+	if ptr.Kind() != reflect.Pointer || ptr.IsNil() || ptr.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("Load requires a non-nil pointer to a struct, got %T", cfg)
+	}
+
+	b := &binder{
+		opts:    opts,
+		envVars: envVars,
+		groups:  make(map[string]*fieldGroup),
+		used:    make(map[string]bool),
+	}
+	b.mode, _ = b.lookup(opts.modeVariable())
+
+	val := ptr.Elem()
+	if err := checkTags(val.Type(), opts); err != nil {
+		return err
+	}
+	if err := b.checkDuplicateNames(val.Type(), opts.GlobalPrefix, "", make(map[string]string)); err != nil {
+		return err
+	}
+	if err := b.checkAdditionalRequired(); err != nil {
+		return err
+	}
+	if err := b.parseStruct(val, opts.GlobalPrefix); err != nil {
+		return err
+	}
+	if err := b.resolveRefs(); err != nil {
+		return err
+	}
+	if err := b.validateFields(); err != nil {
+		return err
+	}
+	b.fillCatchAll()
+	if opts.WarnUnknownKeys {
+		b.warnUnknownKeys()
+	}
+	return b.validateGroups()
+}
+
+// fillCatchAll hands every parsed variable to the fields tagged env:"*",
+// leaving out those read by other fields with Options.CatchAllUnused.
+func (b *binder) fillCatchAll() {
+	for _, field := range b.catchAll {
+		all := make(map[string]string, len(b.envVars))
+		for key, val := range b.envVars {
+			if b.opts.CatchAllUnused && b.used[key] {
+				continue
+			}
+			all[key] = val
+		}
+		field.Set(reflect.ValueOf(all))
+	}
+}
+
+// warnUnknownKeys warns about file variables that no field consumed.
+func (b *binder) warnUnknownKeys() {
+	keys := make([]string, 0, len(b.envVars))
+	for key := range b.envVars {
+		if !b.used[key] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		b.opts.warn(key, "unknown key %s", key)
+	}
+}
+
+// parseStruct binds the fields of val, prepending prefix to every env name.
+// Nested structs tagged `envPrefix` extend the prefix for their fields.
+func (b *binder) parseStruct(val reflect.Value, prefix string) error {
 	typ := val.Type()
 
 	for i := 0; i < val.NumField(); i++ {
 		field := val.Field(i)
 		structField := typ.Field(i)
 
-		if field.Kind() == reflect.Struct {
-			if err := parseEnv(field.Addr().Interface(), envVars); err != nil {
+		if field.Kind() == reflect.Struct && structField.Tag.Get("inline") == "true" {
+			envValue, source, err := b.getValueFromEnvOrFile(structField, prefix)
+			if err != nil {
+				return err
+			}
+			if source == sourceUnset {
+				continue
+			}
+			if err := b.parseInline(field, envValue); err != nil {
+				return fieldError(structField, err)
+			}
+			continue
+		}
+
+		if field.Kind() == reflect.Struct && !isScalarStruct(field) {
+			if err := b.parseStruct(field, b.nestedPrefix(prefix, structField)); err != nil {
 				return err
 			}
+			if !field.CanInterface() {
+				// An embedded unexported struct still promotes its
+				// exported fields, but cannot be a CustomParser itself.
+				continue
+			}
 			if customParser, ok := field.Addr().Interface().(CustomParser); ok {
-				envValue, err := getValueFromEnvOrFile(structField, envVars)
+				envValue, _, err := b.getValueFromEnvOrFile(structField, prefix)
 				if err != nil {
 					return err
 				}
 				if err := customParser.ParseEnv(envValue); err != nil {
-					return err
+					return redactSecret(structField, err)
 				}
 			}
 			continue
 		}
 
-		envValue, err := getValueFromEnvOrFile(structField, envVars)
+		if structField.Tag.Get("env") == catchAllTag {
+			if field.Type() != reflect.TypeOf(map[string]string(nil)) {
+				return fmt.Errorf("field %s: env:%q requires a map[string]string", structField.Name, catchAllTag)
+			}
+			b.catchAll = append(b.catchAll, field)
+			continue
+		}
+
+		if field.Kind() == reflect.Interface && hasImpls(field.Type()) {
+			key, source, err := b.getValueFromEnvOrFile(structField, prefix)
+			if err != nil {
+				return err
+			}
+			if source == sourceUnset {
+				continue
+			}
+			if err := b.parseImpl(field, key, b.nestedPrefix(prefix, structField)); err != nil {
+				return fieldError(structField, err)
+			}
+			continue
+		}
+
+		if structField.Tag.Get("indexed") == "true" && field.Kind() == reflect.Slice {
+			if err := b.parseIndexed(field, prefix+b.envName(structField)); err != nil {
+				return fieldError(structField, err)
+			}
+			continue
+		}
+
+		if b.opts.CheckDefaults {
+			if err := b.checkDefault(field, structField); err != nil {
+				return err
+			}
+		}
+
+		envValue, source, err := b.getValueFromEnvOrFile(structField, prefix)
 		if err != nil {
 			return err
 		}
+		b.recordGroup(structField, prefix+b.envName(structField), source == sourceEnv)
+		b.bound = append(b.bound, boundField{parent: val, index: i, name: prefix + b.envName(structField), set: source != sourceUnset})
+
+		if path := structField.Tag.Get("flag_file"); path != "" && field.Kind() == reflect.Bool {
+			exists, err := flagFileExists(path)
+			if err != nil {
+				return fieldError(structField, err)
+			}
+			if exists {
+				field.SetBool(true)
+				b.bound[len(b.bound)-1].set = true
+				continue
+			}
+		}
 
-		if envValue == "" {
+		if source == sourceUnset {
 			continue
 		}
+		if source == sourceDefault && strings.HasPrefix(envValue, "@") {
+			if ref, ok := strings.CutPrefix(envValue, "@"); ok && !strings.HasPrefix(ref, "@") {
+				b.refs = append(b.refs, fieldRef{parent: val, index: i, ref: ref})
+				continue
+			}
+			envValue = envValue[1:]
+		}
 
-		if err := setValue(field, envValue); err != nil {
-			return fmt.Errorf("error setting field %s: %v", structField.Name, err)
+		if envValue == "" && structField.Tag.Get("negate") != "true" {
+			field.Set(reflect.Zero(field.Type()))
+			continue
+		}
+
+		if err := b.setField(field, structField, envValue); err != nil {
+			return fieldError(structField, err)
+		}
+		if err := normalizeSlice(field, structField); err != nil {
+			return fieldError(structField, err)
 		}
 	}
 	return nil
 }
 
-func getValueFromEnvOrFile(structField reflect.StructField, envVars map[string]string) (string, error) {
+// fieldError wraps an error from binding a value to structField.
+func fieldError(structField reflect.StructField, err error) error {
+	return redactSecret(structField, fmt.Errorf("error setting field %s: %v", structField.Name, err))
+}
+
+// redactSecret replaces err with a generic message when structField is
+// tagged `secret:"true"`. Parse and validation errors routinely quote the
+// value, so the whole message goes rather than being scrubbed piecemeal.
+func redactSecret(structField reflect.StructField, err error) error {
+	if err == nil || structField.Tag.Get("secret") != "true" {
+		return err
+	}
+	return fmt.Errorf("field %s: invalid value [REDACTED]", structField.Name)
+}
+
+// setField applies conversions requested by struct tags before falling back
+// to the type-driven setValue.
+func (b *binder) setField(field reflect.Value, structField reflect.StructField, value string) error {
+	value, err := decrypt(value)
+	if err != nil {
+		return err
+	}
+	if structField.Tag.Get("expanduser") == "true" {
+		expanded, err := expandUser(value)
+		if err != nil {
+			return err
+		}
+		value = expanded
+	}
+	if transform := structField.Tag.Get("transform"); transform != "" {
+		transformed, err := applyTransforms(value, transform)
+		if err != nil {
+			return err
+		}
+		value = transformed
+	}
+	if structField.Tag.Get("json_valid") == "true" && !json.Valid([]byte(value)) {
+		return fmt.Errorf("invalid JSON")
+	}
+	if structField.Tag.Get("negate") == "true" {
+		return setNegatedBool(field, value)
+	}
+	if structField.Tag.Get("char") == "true" {
+		return setChar(field, value)
+	}
+	if structField.Tag.Get("duration") == "true" {
+		return setDurationUnits(field, value, structField.Tag.Get("unit"))
+	}
+	if format := structField.Tag.Get("format"); strings.HasPrefix(format, "unix") && field.Type() == timeType {
+		return setUnixTime(field, value, format)
+	}
+	if structField.Tag.Get("format") == "iso8601" && field.Type() == durationType {
+		duration, err := parseISODuration(value)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(duration))
+		return nil
+	}
+	if unit, ok := structField.Tag.Lookup("unit"); ok && field.Type() == durationType {
+		return setDurationFromUnit(field, value, unit)
+	}
+	if b.opts.ThousandsSeparator != "" && isInteger(field) && field.Type() != durationType {
+		value = strings.ReplaceAll(value, b.opts.ThousandsSeparator, "")
+	}
+	if decimal := structField.Tag.Get("decimal"); decimal != "" && decimal != "." && isFloat(field) {
+		value = strings.ReplaceAll(value, decimal, ".")
+	}
+	if b.opts.LenientBools && field.Kind() == reflect.Bool {
+		boolVal, err := parseLenientBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(boolVal)
+		return nil
+	}
+	if name := structField.Tag.Get("parser"); name != "" {
+		return setParsed(field, value, name)
+	}
+	if name := structField.Tag.Get("elem_parser"); name != "" && field.Kind() == reflect.Slice {
+		sep, ok := structField.Tag.Lookup("sep")
+		if !ok {
+			sep = ","
+		}
+		return setSlice(field, value, sep, func(elem reflect.Value, value string) error {
+			return setParsed(elem, value, name)
+		})
+	}
+	if thousands := b.opts.ThousandsSeparator; thousands != "" && field.Kind() == reflect.Slice &&
+		isInteger(reflect.New(field.Type().Elem()).Elem()) && field.Type().Elem() != durationType {
+		sep, ok := structField.Tag.Lookup("sep")
+		if !ok {
+			sep = ","
+		}
+		return setSlice(field, value, sep, func(elem reflect.Value, value string) error {
+			return setValue(elem, strings.ReplaceAll(value, thousands, ""))
+		})
+	}
+	if sep, ok := structField.Tag.Lookup("sep"); ok && field.Kind() == reflect.Slice {
+		return setSlice(field, value, sep, setValue)
+	}
+	if field.Kind() == reflect.Map {
+		mapSep, hasMapSep := structField.Tag.Lookup("map_sep")
+		kvSep, hasKVSep := structField.Tag.Lookup("kv_sep")
+		if hasMapSep || hasKVSep {
+			return setSeparatedMap(field, value, mapSep, kvSep)
+		}
+	}
+	return setValue(field, value)
+}
+
+// parseLenientBool accepts the strconv.ParseBool forms plus any integer,
+// treating non-zero as true.
+func parseLenientBool(value string) (bool, error) {
+	if boolVal, err := strconv.ParseBool(value); err == nil {
+		return boolVal, nil
+	}
+	intVal, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return false, fmt.Errorf("invalid boolean %q", value)
+	}
+	return intVal != 0, nil
+}
+
+func isFloat(field reflect.Value) bool {
+	return field.Kind() == reflect.Float32 || field.Kind() == reflect.Float64
+}
+
+func isInteger(field reflect.Value) bool {
+	switch field.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+// nestedPrefix returns the prefix for the fields of a nested struct, joining
+// its `envPrefix` tag to the parent prefix with Options.PrefixSeparator.
+func (b *binder) nestedPrefix(prefix string, structField reflect.StructField) string {
+	envPrefix := structField.Tag.Get("envPrefix")
+	if envPrefix == "" {
+		return prefix
+	}
+	return prefix + envPrefix + b.opts.PrefixSeparator
+}
+
+func (b *binder) lookup(name string) (string, bool) {
+	val, _, exists := b.lookupSource(name)
+	return val, exists
+}
+
+// lookupSource is lookup that also names the layer the value was found
+// in: the overlay, the file that last set the matching key, or the
+// process environment.
+func (b *binder) lookupSource(name string) (string, string, bool) {
+	if val, exists := b.opts.Overlay[name]; exists {
+		return val, SourceOverlay, true
+	}
+	if val, exists := b.envVars[name]; exists {
+		b.used[name] = true
+		return val, b.opts.origins[name], true
+	}
+	if val, exists := b.lookupOS(name); exists {
+		return val, SourceOSEnv, true
+	}
+	if osEnvFold {
+		if val, exists := lookupOSFold(name); exists {
+			return val, SourceOSEnv, true
+		}
+	}
+	if b.opts.CaseInsensitive {
+		if val, key, exists := b.lookupFold(name); exists {
+			if key == "" {
+				return val, SourceOSEnv, true
+			}
+			return val, b.opts.origins[key], true
+		}
+	}
+	if val, exists := b.opts.base[name]; exists {
+		return val, b.opts.origins[name], true
+	}
+	return "", "", false
+}
+
+// lookupOS reads name from the process environment, preferring the name
+// with Options.OSPrefix in front when one is set.
+func (b *binder) lookupOS(name string) (string, bool) {
+	if b.opts.OSPrefix != "" {
+		if val, exists := os.LookupEnv(b.opts.OSPrefix + name); exists {
+			return val, true
+		}
+	}
+	return os.LookupEnv(name)
+}
+
+// lookupFold finds name ignoring case, preferring file variables and, among
+// several spellings, the one that sorts first. It also returns the file
+// key that matched, or "" when the value came from the process environment.
+func (b *binder) lookupFold(name string) (string, string, bool) {
+	keys := make([]string, 0, len(b.envVars))
+	for key := range b.envVars {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if strings.EqualFold(key, name) {
+			b.used[key] = true
+			return b.envVars[key], key, true
+		}
+	}
+
+	val, exists := lookupOSFold(name)
+	return val, "", exists
+}
+
+// lookupOSFold finds name in the process environment ignoring case.
+func lookupOSFold(name string) (string, bool) {
+	for _, kv := range os.Environ() {
+		if key, val, ok := strings.Cut(kv, "="); ok && strings.EqualFold(key, name) {
+			return val, true
+		}
+	}
+	return "", false
+}
+
+// osEnvFold makes process environment lookups ignore case, matching
+// Windows, where variable names are case-insensitive. It is a variable so
+// tests can exercise that behaviour on other platforms.
+var osEnvFold = runtime.GOOS == "windows"
+
+// envName returns the variable name for structField: its `env` tag or,
+// when enabled, its `json` tag or Go name in SCREAMING_SNAKE_CASE.
+// `env:"-"` opts a field out.
+func (b *binder) envName(structField reflect.StructField) string {
 	envTag := structField.Tag.Get("env")
+	if envTag == "-" {
+		return ""
+	}
+	if envTag != "" {
+		return envTag
+	}
+	if b.opts.JSONTagFallback {
+		name, _, _ := strings.Cut(structField.Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			return screamingSnake(name)
+		}
+	}
+	if b.opts.DeriveNames && structField.IsExported() {
+		return screamingSnake(structField.Name)
+	}
+	return ""
+}
+
+// getValueFromEnvOrFile resolves the value for structField and reports
+// where it came from, so that an explicitly empty value can be told apart
+// from a missing one.
+func (b *binder) getValueFromEnvOrFile(structField reflect.StructField, prefix string) (string, valueSource, error) {
+	envTag := b.envName(structField)
 	if envTag == "" {
-		return "", nil
+		return "", sourceUnset, nil
 	}
+	envTag = prefix + envTag
 
-	if val, exists := envVars[envTag]; exists {
-		return val, nil
+	if val, exists := b.lookupRaw(structField, envTag); exists {
+		b.opts.Provenance.record(envTag, b.opts.origins[envTag])
+		return val, sourceEnv, nil
 	}
-	if val := os.Getenv(envTag); val != "" {
-		return val, nil
+	// An empty negated variable counts as unset, following the NO_COLOR
+	// convention that only a non-empty value has an effect.
+	negate := structField.Tag.Get("negate") == "true"
+	if val, source, exists := b.lookupSource(envTag); exists && (val != "" || !negate) {
+		if structField.Tag.Get("append") == "true" {
+			if def := b.defaultValue(structField); def != "" && val != "" {
+				sep, ok := structField.Tag.Lookup("sep")
+				if !ok {
+					sep = ","
+				}
+				val = def + sep + val
+			}
+		}
+		b.opts.Provenance.record(envTag, source)
+		return val, sourceEnv, nil
+	}
+	if val, exists := b.lookupAlias(structField, prefix, envTag); exists {
+		return val, sourceEnv, nil
 	}
 	if structField.Tag.Get("required") == "true" {
-		return "", fmt.Errorf("required environment variable %s is missing", envTag)
+		if msg := structField.Tag.Get("required_msg"); msg != "" {
+			return "", sourceUnset, errors.New(msg)
+		}
+		return "", sourceUnset, fmt.Errorf("required environment variable %s is missing", envTag)
 	}
-	return structField.Tag.Get("default"), nil
+	if val := b.defaultValue(structField); val != "" {
+		b.opts.Provenance.record(envTag, SourceDefault)
+		return val, sourceDefault, nil
+	}
+	return "", sourceUnset, nil
+}
+
+// lookupRaw returns the file value of a field tagged `raw:"true"` exactly
+// as written after the =, without quote, escape or ${VAR} processing.
+func (b *binder) lookupRaw(structField reflect.StructField, name string) (string, bool) {
+	if structField.Tag.Get("raw") != "true" {
+		return "", false
+	}
+	if _, exists := b.opts.Overlay[name]; exists {
+		return "", false
+	}
+	val, exists := b.opts.rawValues[name]
+	if exists {
+		b.used[name] = true
+	}
+	return val, exists
+}
+
+// lookupAlias tries the legacy names in the `aliases` tag in order and
+// warns when one of them supplies the value of envTag.
+func (b *binder) lookupAlias(structField reflect.StructField, prefix, envTag string) (string, bool) {
+	aliases := structField.Tag.Get("aliases")
+	if aliases == "" {
+		return "", false
+	}
+	for _, alias := range strings.Split(aliases, ",") {
+		alias = prefix + strings.TrimSpace(alias)
+		if val, source, exists := b.lookupSource(alias); exists && (val != "" || structField.Tag.Get("negate") != "true") {
+			b.opts.warn(alias, "%s is deprecated, use %s instead", alias, envTag)
+			b.opts.Provenance.record(envTag, source)
+			return val, true
+		}
+	}
+	return "", false
+}
+
+// defaultValue prefers a `default_<mode>` tag matching the current mode
+// over the plain `default` tag.
+func (b *binder) defaultValue(structField reflect.StructField) string {
+	if b.mode != "" {
+		if val, ok := structField.Tag.Lookup("default_" + b.mode); ok {
+			return b.expandDefault(val)
+		}
+	}
+	return b.expandDefault(structField.Tag.Get("default"))
+}
+
+// expandDefault resolves $VAR and ${VAR} references in a default tag,
+// keeping unresolved references as written.
+func (b *binder) expandDefault(value string) string {
+	return defaultVarRegex.ReplaceAllStringFunc(value, func(match string) string {
+		name := strings.Trim(match[1:], "{}")
+		if val, exists := b.lookup(name); exists {
+			return val
+		}
+		return match
+	})
+}
+
+// isScalarStruct reports whether a struct field is set from a single value,
+// like sql.NullString or big.Int, rather than recursed into.
+func isScalarStruct(field reflect.Value) bool {
+	if field.Type() == ipNetType || isPEMType(field.Type()) {
+		return true
+	}
+	if !field.CanInterface() {
+		return false
+	}
+	switch field.Addr().Interface().(type) {
+	case sql.Scanner, encoding.TextUnmarshaler:
+		return true
+	}
+	return false
 }
 
 func setValue(field reflect.Value, value string) error {
+	if ok, err := setEnum(field, value); ok {
+		return err
+	}
+	if field.CanAddr() {
+		switch target := field.Addr().Interface().(type) {
+		case sql.Scanner:
+			return target.Scan(value)
+		case encoding.TextUnmarshaler:
+			return target.UnmarshalText([]byte(value))
+		}
+	}
+
+	if field.Type() == rawMessageType {
+		field.SetBytes([]byte(value))
+		return nil
+	}
+	if isPEMType(field.Type()) {
+		return setPEM(field, value)
+	}
+	if field.Type() == ipNetType {
+		_, ipNet, err := net.ParseCIDR(value)
+		if err != nil {
+			return err
+		}
+		field.Set(reflect.ValueOf(*ipNet))
+		return nil
+	}
+
 	switch field.Kind() {
 	case reflect.String:
 		field.SetString(value)
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		if field.Type() == reflect.TypeOf(time.Duration(0)) {
-			duration, err := time.ParseDuration(value)
+		if field.Type() == durationType {
+			duration, err := parseDuration(value)
 			if err != nil {
 				return err
 			}
 			field.SetInt(int64(duration))
 		} else {
-			intVal, err := strconv.ParseInt(value, 10, 64)
+			intVal, err := strconv.ParseInt(value, 10, field.Type().Bits())
 			if err != nil {
 				return err
 			}
 			field.SetInt(intVal)
 		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		uintVal, err := strconv.ParseUint(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(uintVal)
+	case reflect.Float32, reflect.Float64:
+		floatVal, err := strconv.ParseFloat(value, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetFloat(floatVal)
 	case reflect.Bool:
 		boolVal, err := strconv.ParseBool(value)
 		if err != nil {
@@ -214,27 +1139,429 @@ func setValue(field reflect.Value, value string) error {
 		}
 		field.SetBool(boolVal)
 	case reflect.Slice:
-		elements := strings.Split(value, ",")
-		slice := reflect.MakeSlice(field.Type(), len(elements), len(elements))
-		for i, elem := range elements {
-			elem = strings.TrimSpace(elem)
-			if err := setValue(slice.Index(i), elem); err != nil {
-				return err
-			}
+		if isComposite(field.Type().Elem()) {
+			// Nesting such as [][]int cannot be expressed with one separator.
+			return unmarshalJSON(field, value)
 		}
-		field.Set(slice)
+		return setSlice(field, value, ",", setValue)
 	case reflect.Map:
-		var m map[string]string
-		if err := json.Unmarshal([]byte(value), &m); err != nil {
+		return unmarshalJSON(field, value)
+	case reflect.Pointer:
+		elem := reflect.New(field.Type().Elem())
+		if err := setValue(elem.Elem(), value); err != nil {
 			return err
 		}
-		field.Set(reflect.ValueOf(m))
+		field.Set(elem)
 	default:
 		return fmt.Errorf("unsupported type %s", field.Kind())
 	}
 	return nil
 }
 
+// setSlice splits value on sep and sets each element with set. An empty
+// sep keeps value whole as a single element.
+func setSlice(field reflect.Value, value, sep string, set func(reflect.Value, string) error) error {
+	elements := []string{value}
+	if sep != "" {
+		elements = strings.Split(value, sep)
+	}
+	slice := reflect.MakeSlice(field.Type(), len(elements), len(elements))
+	for i, elem := range elements {
+		elem = unquote(strings.TrimSpace(elem))
+		if err := set(slice.Index(i), elem); err != nil {
+			return fmt.Errorf("element %d: %v", i, err)
+		}
+	}
+	field.Set(slice)
+	return nil
+}
+
+// isComposite reports whether values of typ hold other values and so
+// cannot be read from a comma-separated list element.
+func isComposite(typ reflect.Type) bool {
+	if reflect.PointerTo(typ).Implements(textUnmarshalerType) {
+		return false
+	}
+	switch typ.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return true
+	}
+	return false
+}
+
+// unmarshalJSON decodes value as JSON into a new value of field's type.
+func unmarshalJSON(field reflect.Value, value string) error {
+	v := reflect.New(field.Type())
+	if err := json.Unmarshal([]byte(value), v.Interface()); err != nil {
+		return err
+	}
+	field.Set(v.Elem())
+	return nil
+}
+
+// flagFileExists reports whether the flag file at path exists. Errors
+// other than its absence, such as a permission problem, are returned.
+func flagFileExists(path string) (bool, error) {
+	if _, err := os.Stat(path); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// expandUser replaces a leading ~ or ~/ with the user's home directory.
+// Tildes anywhere else, or followed by a user name, are left alone.
+func expandUser(value string) (string, error) {
+	if value != "~" && !strings.HasPrefix(value, "~/") {
+		return value, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return home + value[1:], nil
+}
+
+// applyTransforms normalizes value with the comma-separated transforms
+// named in a `transform` tag: upper, lower and trim.
+func applyTransforms(value, transforms string) (string, error) {
+	for _, transform := range strings.Split(transforms, ",") {
+		switch strings.TrimSpace(transform) {
+		case "upper":
+			value = strings.ToUpper(value)
+		case "lower":
+			value = strings.ToLower(value)
+		case "trim":
+			value = strings.TrimSpace(value)
+		default:
+			return "", fmt.Errorf("unknown transform %q", transform)
+		}
+	}
+	return value, nil
+}
+
+// normalizeSlice applies the `unique` and `sort` tags to a slice field.
+// unique:"true" drops repeated elements, keeping the first, while
+// unique:"error" rejects them.
+func normalizeSlice(field reflect.Value, structField reflect.StructField) error {
+	unique := structField.Tag.Get("unique")
+	dedupe := unique == "true" || unique == "error"
+	sorted := structField.Tag.Get("sort") == "true"
+	if field.Kind() != reflect.Slice || (!dedupe && !sorted) {
+		return nil
+	}
+
+	if dedupe {
+		if !field.Type().Elem().Comparable() {
+			return fmt.Errorf("unique tag requires comparable elements, got %s", field.Type().Elem())
+		}
+		seen := make(map[any]bool, field.Len())
+		deduped := reflect.MakeSlice(field.Type(), 0, field.Len())
+		for i := 0; i < field.Len(); i++ {
+			elem := field.Index(i)
+			if seen[elem.Interface()] {
+				if unique == "error" {
+					return fmt.Errorf("duplicate element %v", elem.Interface())
+				}
+				continue
+			}
+			seen[elem.Interface()] = true
+			deduped = reflect.Append(deduped, elem)
+		}
+		field.Set(deduped)
+	}
+
+	if sorted {
+		less, err := sliceLess(field)
+		if err != nil {
+			return err
+		}
+		sort.SliceStable(field.Interface(), less)
+	}
+	return nil
+}
+
+func sliceLess(slice reflect.Value) (func(i, j int) bool, error) {
+	switch slice.Type().Elem().Kind() {
+	case reflect.String:
+		return func(i, j int) bool { return slice.Index(i).String() < slice.Index(j).String() }, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return func(i, j int) bool { return slice.Index(i).Int() < slice.Index(j).Int() }, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return func(i, j int) bool { return slice.Index(i).Uint() < slice.Index(j).Uint() }, nil
+	case reflect.Float32, reflect.Float64:
+		return func(i, j int) bool { return slice.Index(i).Float() < slice.Index(j).Float() }, nil
+	}
+	return nil, fmt.Errorf("sort tag requires ordered elements, got %s", slice.Type().Elem())
+}
+
+// setChar stores the code point of value, which must be a single
+// character, in a rune or byte field. Digits are characters too, so
+// SEPARATOR=1 stores '1' rather than 1.
+func setChar(field reflect.Value, value string) error {
+	char, size := utf8.DecodeRuneInString(value)
+	if size == 0 || size != len(value) || char == utf8.RuneError {
+		return fmt.Errorf("expected a single character, got %q", value)
+	}
+	switch field.Kind() {
+	case reflect.Int32:
+		field.SetInt(int64(char))
+	case reflect.Uint8:
+		if char > math.MaxUint8 {
+			return fmt.Errorf("character %q does not fit in a byte", value)
+		}
+		field.SetUint(uint64(char))
+	default:
+		return fmt.Errorf("char tag requires a rune or byte field, got %s", field.Type())
+	}
+	return nil
+}
+
+// setNegatedBool stores the inverse of value, so that NO_COLOR=1 turns a
+// Color field off. An empty value leaves field as is; lookups already treat
+// an empty negated variable as unset, so its default applies.
+func setNegatedBool(field reflect.Value, value string) error {
+	if field.Kind() != reflect.Bool {
+		return fmt.Errorf("negate tag requires a bool field, got %s", field.Kind())
+	}
+	if value == "" {
+		return nil
+	}
+	boolVal, err := strconv.ParseBool(value)
+	if err != nil {
+		return err
+	}
+	field.SetBool(!boolVal)
+	return nil
+}
+
+// setSeparatedMap parses "k=v,k2=v2" style values into a map, converting
+// keys and values to the map's element types via setValue.
+func setSeparatedMap(field reflect.Value, value, mapSep, kvSep string) error {
+	if mapSep == "" {
+		mapSep = ","
+	}
+	if kvSep == "" {
+		kvSep = "="
+	}
+
+	typ := field.Type()
+	m := reflect.MakeMap(typ)
+	for _, pair := range strings.Split(value, mapSep) {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		kv := strings.SplitN(pair, kvSep, 2)
+		if len(kv) != 2 {
+			return fmt.Errorf("invalid map entry %q", pair)
+		}
+
+		key := reflect.New(typ.Key()).Elem()
+		if err := setValue(key, strings.TrimSpace(kv[0])); err != nil {
+			return fmt.Errorf("invalid map key %q: %v", kv[0], err)
+		}
+		elem := reflect.New(typ.Elem()).Elem()
+		if err := setValue(elem, strings.TrimSpace(kv[1])); err != nil {
+			return fmt.Errorf("invalid value for map key %q: %v", kv[0], err)
+		}
+		m.SetMapIndex(key, elem)
+	}
+	field.Set(m)
+	return nil
+}
+
+// setUnixTime sets a time.Time field from an epoch timestamp in the unit
+// named by format: unix for seconds, unixmilli or unixnano.
+func setUnixTime(field reflect.Value, value, format string) error {
+	epoch, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid Unix timestamp %q", value)
+	}
+
+	var t time.Time
+	switch format {
+	case "unix":
+		t = time.Unix(epoch, 0)
+	case "unixmilli":
+		t = time.UnixMilli(epoch)
+	case "unixnano":
+		t = time.Unix(0, epoch)
+	default:
+		return fmt.Errorf("unknown format %q", format)
+	}
+	field.Set(reflect.ValueOf(t))
+	return nil
+}
+
+// parseISODuration parses ISO 8601 durations such as PT1H30M or P1DT12H.
+// Weeks count as 7 days and days as 24 hours; years and months have no
+// fixed length and are rejected.
+func parseISODuration(value string) (time.Duration, error) {
+	rest, negative := strings.CutPrefix(value, "-")
+	rest, ok := strings.CutPrefix(rest, "P")
+	if !ok || rest == "" || strings.HasSuffix(rest, "T") {
+		return 0, fmt.Errorf("invalid ISO 8601 duration %q", value)
+	}
+
+	var goDuration strings.Builder
+	if negative {
+		goDuration.WriteByte('-')
+	}
+	units, inTime := "YMWD", false
+	for rest != "" {
+		if rest[0] == 'T' && !inTime {
+			units, inTime = "HMS", true
+			rest = rest[1:]
+			continue
+		}
+		numEnd := strings.IndexFunc(rest, func(r rune) bool { return (r < '0' || r > '9') && r != '.' && r != ',' })
+		if numEnd <= 0 {
+			return 0, fmt.Errorf("invalid ISO 8601 duration %q", value)
+		}
+		number, err := strconv.ParseFloat(strings.Replace(rest[:numEnd], ",", ".", 1), 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid ISO 8601 duration %q", value)
+		}
+		unit := rest[numEnd]
+		rest = rest[numEnd+1:]
+
+		// Designators must appear once each, in order.
+		pos := strings.IndexByte(units, unit)
+		if pos < 0 {
+			return 0, fmt.Errorf("invalid ISO 8601 duration %q", value)
+		}
+		units = units[pos+1:]
+
+		switch {
+		case !inTime && (unit == 'Y' || unit == 'M'):
+			return 0, fmt.Errorf("ISO 8601 duration %q uses years or months, which have no fixed length", value)
+		case !inTime && unit == 'W':
+			goDuration.WriteString(strconv.FormatFloat(number*7*24, 'f', -1, 64) + "h")
+		case !inTime && unit == 'D':
+			goDuration.WriteString(strconv.FormatFloat(number*24, 'f', -1, 64) + "h")
+		default:
+			goDuration.WriteString(strconv.FormatFloat(number, 'f', -1, 64) + strings.ToLower(string(unit)))
+		}
+	}
+	return time.ParseDuration(goDuration.String())
+}
+
+// parseDuration extends time.ParseDuration with d (24h) and w (7d) units,
+// so "30d" and "1w2d12h" are accepted.
+func parseDuration(value string) (time.Duration, error) {
+	if !strings.ContainsAny(value, "dw") {
+		return time.ParseDuration(value)
+	}
+
+	rest := value
+	sign := time.Duration(1)
+	if rest != "" && (rest[0] == '-' || rest[0] == '+') {
+		if rest[0] == '-' {
+			sign = -1
+		}
+		rest = rest[1:]
+	}
+
+	var total time.Duration
+	for rest != "" {
+		numEnd := strings.IndexFunc(rest, func(r rune) bool { return (r < '0' || r > '9') && r != '.' })
+		if numEnd <= 0 {
+			return 0, fmt.Errorf("invalid duration %q", value)
+		}
+		unitEnd := strings.IndexFunc(rest[numEnd:], func(r rune) bool { return (r >= '0' && r <= '9') || r == '.' })
+		if unitEnd < 0 {
+			unitEnd = len(rest) - numEnd
+		}
+		number, unit := rest[:numEnd], rest[numEnd:numEnd+unitEnd]
+		rest = rest[numEnd+unitEnd:]
+
+		var scale time.Duration
+		switch unit {
+		case "d":
+			scale = 24 * time.Hour
+		case "w":
+			scale = 7 * 24 * time.Hour
+		default:
+			part, err := time.ParseDuration(number + unit)
+			if err != nil || part > math.MaxInt64-total {
+				return 0, fmt.Errorf("invalid duration %q", value)
+			}
+			total += part
+			continue
+		}
+
+		count, err := strconv.ParseFloat(number, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", value)
+		}
+		// Reject values outside the Duration range, as time.ParseDuration
+		// does, instead of letting the conversion wrap around.
+		part := count * float64(scale)
+		if part >= math.MaxInt64 || time.Duration(part) > math.MaxInt64-total {
+			return 0, fmt.Errorf("invalid duration %q", value)
+		}
+		total += time.Duration(part)
+	}
+	return sign * total, nil
+}
+
+var durationUnits = map[string]time.Duration{
+	"":   time.Nanosecond,
+	"ns": time.Nanosecond,
+	"us": time.Microsecond,
+	"µs": time.Microsecond,
+	"ms": time.Millisecond,
+	"s":  time.Second,
+	"m":  time.Minute,
+	"h":  time.Hour,
+}
+
+// setDurationUnits parses value as a duration and stores it in an integer
+// field counted in unit, e.g. "1.5s" becomes 1500 with unit "ms".
+func setDurationUnits(field reflect.Value, value, unit string) error {
+	scale, ok := durationUnits[unit]
+	if !ok {
+		return fmt.Errorf("unknown duration unit %q", unit)
+	}
+	duration, err := parseDuration(value)
+	if err != nil {
+		return err
+	}
+
+	switch field.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		field.SetInt(int64(duration / scale))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if duration < 0 {
+			return fmt.Errorf("negative duration %s for unsigned field", value)
+		}
+		field.SetUint(uint64(duration / scale))
+	default:
+		return fmt.Errorf("duration tag requires an integer field, got %s", field.Kind())
+	}
+	return nil
+}
+
+// setDurationFromUnit fills a time.Duration field, reading a bare integer as
+// a count of unit and anything else as a regular duration string.
+func setDurationFromUnit(field reflect.Value, value, unit string) error {
+	scale, ok := durationUnits[unit]
+	if !ok {
+		return fmt.Errorf("unknown duration unit %q", unit)
+	}
+	if count, err := strconv.ParseInt(value, 10, 64); err == nil {
+		if count > math.MaxInt64/int64(scale) || count < math.MinInt64/int64(scale) {
+			return fmt.Errorf("duration %s%s overflows", value, unit)
+		}
+		field.SetInt(count * int64(scale))
+		return nil
+	}
+	return setValue(field, value)
+}
+
 type CustomParser interface {
 	ParseEnv(value string) error
 }
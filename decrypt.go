@@ -0,0 +1,47 @@
+package environment
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// encryptedPrefix marks a value to be passed to the registered decryptor.
+const encryptedPrefix = "enc:"
+
+var (
+	decryptorMu sync.RWMutex
+	decryptor   func(ciphertext string) (string, error)
+)
+
+// RegisterDecryptor sets the function that decrypts values written as
+// enc:<ciphertext>, such as age or gpg output kept in a .env file. It
+// receives the text after the prefix. Until one is registered, enc: values
+// are read verbatim; passing nil turns decryption off again.
+func RegisterDecryptor(fn func(ciphertext string) (string, error)) {
+	decryptorMu.Lock()
+	defer decryptorMu.Unlock()
+	decryptor = fn
+}
+
+// decrypt returns value with an enc: prefix replaced by its plaintext when
+// a decryptor is registered. Errors never include the ciphertext.
+func decrypt(value string) (string, error) {
+	ciphertext, ok := strings.CutPrefix(value, encryptedPrefix)
+	if !ok {
+		return value, nil
+	}
+
+	decryptorMu.RLock()
+	fn := decryptor
+	decryptorMu.RUnlock()
+	if fn == nil {
+		return value, nil
+	}
+
+	plaintext, err := fn(ciphertext)
+	if err != nil {
+		return "", fmt.Errorf("cannot decrypt value: %v", err)
+	}
+	return plaintext, nil
+}
@@ -0,0 +1,46 @@
+package environment
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRegisterDecryptor(t *testing.T) {
+	type Config struct {
+		Password string `env:"PASSWORD" secret:"true"`
+		User     string `env:"USER_NAME"`
+	}
+	envVars := map[string]string{"PASSWORD": "enc:s3cr3t", "USER_NAME": "admin"}
+
+	var cfg Config
+	if err := parseEnv(&cfg, envVars, Options{}); err != nil {
+		t.Fatalf("parseEnv failed: %v", err)
+	}
+	if cfg.Password != "enc:s3cr3t" {
+		t.Errorf("Expected value verbatim without a decryptor, got %q", cfg.Password)
+	}
+
+	RegisterDecryptor(func(ciphertext string) (string, error) {
+		if ciphertext == "bad" {
+			return "", errors.New("wrong key")
+		}
+		return strings.ToUpper(ciphertext), nil
+	})
+	t.Cleanup(func() { RegisterDecryptor(nil) })
+
+	if err := parseEnv(&cfg, envVars, Options{}); err != nil {
+		t.Fatalf("parseEnv failed: %v", err)
+	}
+	if cfg.Password != "S3CR3T" {
+		t.Errorf("Expected decrypted value, got %q", cfg.Password)
+	}
+	if cfg.User != "admin" {
+		t.Errorf("Expected plain value untouched, got %q", cfg.User)
+	}
+
+	err := parseEnv(&cfg, map[string]string{"PASSWORD": "enc:bad"}, Options{})
+	if err == nil || strings.Contains(err.Error(), "bad") {
+		t.Errorf("Expected redacted decryption error, got %v", err)
+	}
+}
@@ -3,14 +3,19 @@
 
 package environment
 
-import (
-	"log"
-)
-
-const defaultEnvironmentFile = ".env"
+import "os"
 
 func RegisterEnvironment[T any](instance *T) {
-	if err := fillSpecification[T](instance, defaultEnvironmentFile); err != nil {
-		log.Fatalf("%v", err)
+	if err := fillSpecification(instance, Options{}, environmentFiles()...); err != nil {
+		OnFatal(err)
+	}
+}
+
+// environmentFiles returns the file named by DOTENV_PATH, falling back to
+// .env when it is unset.
+func environmentFiles() []string {
+	if path := os.Getenv(dotenvPathVariable); path != "" {
+		return []string{path}
 	}
+	return []string{defaultEnvironmentFile}
 }
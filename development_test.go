@@ -0,0 +1,18 @@
+//go:build development
+// +build development
+
+package environment
+
+import "testing"
+
+func TestEnvironmentFilesDotenvPath(t *testing.T) {
+	t.Setenv(dotenvPathVariable, "")
+	if files := environmentFiles(); len(files) != 1 || files[0] != defaultEnvironmentFile {
+		t.Errorf("Expected [%s], got %v", defaultEnvironmentFile, files)
+	}
+
+	t.Setenv(dotenvPathVariable, "config/staging.env")
+	if files := environmentFiles(); len(files) != 1 || files[0] != "config/staging.env" {
+		t.Errorf("Expected [config/staging.env], got %v", files)
+	}
+}
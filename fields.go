@@ -0,0 +1,238 @@
+package environment
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// walkFields calls fn for every field reachable from val that reads a
+// variable, with its name resolved as parseEnv resolves it under opts:
+// Options.GlobalPrefix, `envPrefix` tags joined by Options.PrefixSeparator,
+// and the JSON tag or derived name fallbacks. Walking stops early when fn
+// returns false.
+func walkFields(val reflect.Value, opts Options, fn func(field reflect.Value, structField reflect.StructField, name string) bool) {
+	b := &binder{opts: opts}
+	b.walkFields(val, opts.GlobalPrefix, fn)
+}
+
+func (b *binder) walkFields(val reflect.Value, prefix string, fn func(field reflect.Value, structField reflect.StructField, name string) bool) bool {
+	typ := val.Type()
+	for i := 0; i < val.NumField(); i++ {
+		field := val.Field(i)
+		structField := typ.Field(i)
+
+		nested := field.Kind() == reflect.Struct && structField.Tag.Get("inline") != "true" &&
+			!isScalarStruct(reflect.New(field.Type()).Elem())
+		// A nested struct only reads a variable itself through an
+		// explicit env tag, never a derived name.
+		if name := b.envName(structField); name != "" && (!nested || structField.Tag.Get("env") != "") {
+			if !fn(field, structField, prefix+name) {
+				return false
+			}
+		}
+
+		if nested {
+			if !b.walkFields(field, b.nestedPrefix(prefix, structField), fn) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// FieldByEnv returns the value of the field in v that is populated from the
+// env variable name, with nested `envPrefix` tags concatenated as with the
+// default Options. v must be a struct or a pointer to one.
+func FieldByEnv(v any, name string) (any, bool) {
+	return FieldByEnvWithOptions(v, name, Options{})
+}
+
+// FieldByEnvWithOptions is FieldByEnv with names resolved under opts.
+func FieldByEnvWithOptions(v any, name string, opts Options) (any, bool) {
+	val := reflect.Indirect(reflect.ValueOf(v))
+	if val.Kind() != reflect.Struct {
+		return nil, false
+	}
+
+	var result any
+	var found bool
+	walkFields(val, opts, func(field reflect.Value, _ reflect.StructField, envName string) bool {
+		if envName != name || !field.CanInterface() {
+			return true
+		}
+		result, found = field.Interface(), true
+		return false
+	})
+	return result, found
+}
+
+// EnvVar describes a variable a struct reads.
+type EnvVar struct {
+	Name     string
+	Required bool
+}
+
+// EnvVars lists every variable v reads, in field order, with nested
+// `envPrefix` tags concatenated as with the default Options. Fields tagged
+// `env:"-"` or `env:"*"` are left out. v must be a struct or a pointer to
+// one.
+func EnvVars(v any) []EnvVar {
+	return EnvVarsWithOptions(v, Options{})
+}
+
+// EnvVarsWithOptions is EnvVars with names resolved under opts, so that
+// they match what LoadWithOptions reads.
+func EnvVarsWithOptions(v any, opts Options) []EnvVar {
+	val := reflect.Indirect(reflect.ValueOf(v))
+	if val.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var vars []EnvVar
+	walkFields(val, opts, func(_ reflect.Value, structField reflect.StructField, name string) bool {
+		if structField.Tag.Get("env") != catchAllTag {
+			vars = append(vars, EnvVar{Name: name, Required: structField.Tag.Get("required") == "true"})
+		}
+		return true
+	})
+	return vars
+}
+
+// EnvNames returns the names from EnvVars, e.g. to generate .env.example.
+func EnvNames(v any) []string {
+	return EnvNamesWithOptions(v, Options{})
+}
+
+// EnvNamesWithOptions returns the names from EnvVarsWithOptions.
+func EnvNamesWithOptions(v any, opts Options) []string {
+	vars := EnvVarsWithOptions(v, opts)
+	names := make([]string, len(vars))
+	for i, envVar := range vars {
+		names[i] = envVar.Name
+	}
+	return names
+}
+
+// secretMask replaces the values of fields tagged `secret:"true"` in Dump.
+const secretMask = "******"
+
+// DumpOptions controls the output of Dump.
+type DumpOptions struct {
+	// ShowSecretLength appends the length of masked secrets, as in
+	// "****** (12 chars)". Their characters are never shown.
+	ShowSecretLength bool
+	// Options resolves the variable names, as for LoadWithOptions.
+	Options Options
+}
+
+// Dump formats the fields of v as NAME=value lines in field order, for
+// logging the effective configuration. Values of fields tagged
+// `secret:"true"` are masked. v must be a struct or a pointer to one.
+func Dump(v any, opts DumpOptions) string {
+	val := reflect.Indirect(reflect.ValueOf(v))
+	if val.Kind() != reflect.Struct {
+		return ""
+	}
+
+	var sb strings.Builder
+	walkFields(val, opts.Options, func(field reflect.Value, structField reflect.StructField, name string) bool {
+		if !field.CanInterface() || structField.Tag.Get("env") == catchAllTag {
+			return true
+		}
+		value := ""
+		if field.Kind() != reflect.Pointer || !field.IsNil() {
+			value = fmt.Sprint(reflect.Indirect(field).Interface())
+		}
+		if structField.Tag.Get("secret") == "true" {
+			masked := secretMask
+			if opts.ShowSecretLength {
+				masked += fmt.Sprintf(" (%d chars)", utf8.RuneCountInString(value))
+			}
+			value = masked
+		}
+		fmt.Fprintf(&sb, "%s=%s\n", name, value)
+		return true
+	})
+	return sb.String()
+}
+
+// Reset zeroes every exported field of instance, recursing into nested
+// structs, so it can be reloaded without leftover state. Unexported fields
+// are left untouched. A nil instance, or a T that is not a struct, is a
+// no-op.
+func Reset[T any](instance *T) {
+	if instance == nil {
+		return
+	}
+	if val := reflect.ValueOf(instance).Elem(); val.Kind() == reflect.Struct {
+		resetStruct(val)
+	}
+}
+
+func resetStruct(val reflect.Value) {
+	for i := 0; i < val.NumField(); i++ {
+		field := val.Field(i)
+		if !field.CanSet() {
+			continue
+		}
+		if field.Kind() == reflect.Struct && !isScalarStruct(field) {
+			resetStruct(field)
+			continue
+		}
+		field.Set(reflect.Zero(field.Type()))
+	}
+}
+
+// screamingSnake converts names such as maxConns, max-conns or HTTPPort to
+// MAX_CONNS and HTTP_PORT.
+func screamingSnake(name string) string {
+	runes := []rune(name)
+	var sb strings.Builder
+	for i, r := range runes {
+		if r == '-' || r == '.' || r == ' ' {
+			r = '_'
+		}
+		if i > 0 && unicode.IsUpper(r) {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				sb.WriteRune('_')
+			}
+		}
+		sb.WriteRune(unicode.ToUpper(r))
+	}
+	return sb.String()
+}
+
+// Merge copies every non-zero exported field of override into base,
+// recursing into nested structs, so separately built layers can be
+// combined. Nil pointers, slices and maps in override leave base as is. A
+// nil base or override, or a T that is not a struct, is a no-op.
+func Merge[T any](base, override *T) {
+	if base == nil || override == nil {
+		return
+	}
+	if val := reflect.ValueOf(base).Elem(); val.Kind() == reflect.Struct {
+		mergeStruct(val, reflect.ValueOf(override).Elem())
+	}
+}
+
+func mergeStruct(base, override reflect.Value) {
+	for i := 0; i < base.NumField(); i++ {
+		field := base.Field(i)
+		if !field.CanSet() {
+			continue
+		}
+		src := override.Field(i)
+		if field.Kind() == reflect.Struct && !isScalarStruct(field) {
+			mergeStruct(field, src)
+			continue
+		}
+		if !src.IsZero() {
+			field.Set(src)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package environment
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFieldByEnv(t *testing.T) {
+	type Database struct {
+		Host string `env:"HOST"`
+		Port int    `env:"PORT"`
+	}
+	type Config struct {
+		Name string   `env:"NAME"`
+		DB   Database `envPrefix:"DB_"`
+	}
+
+	cfg := Config{Name: "app", DB: Database{Host: "db.local", Port: 5432}}
+
+	value, ok := FieldByEnv(&cfg, "DB_PORT")
+	if !ok {
+		t.Fatal("Expected DB_PORT to be found")
+	}
+	if value != 5432 {
+		t.Errorf("Expected 5432, got %v", value)
+	}
+
+	if _, ok := FieldByEnv(cfg, "PORT"); ok {
+		t.Error("Expected unprefixed PORT not to be found")
+	}
+}
+
+func TestReset(t *testing.T) {
+	type Database struct {
+		Host  string
+		Ports []int
+		token string
+	}
+	type Config struct {
+		Name  string
+		Debug bool
+		DB    Database
+		Start time.Time
+		state int
+	}
+
+	cfg := Config{
+		Name:  "app",
+		Debug: true,
+		DB:    Database{Host: "db.local", Ports: []int{5432}, token: "secret"},
+		Start: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		state: 7,
+	}
+	Reset(&cfg)
+
+	expected := Config{DB: Database{token: "secret"}, state: 7}
+	if !reflect.DeepEqual(cfg, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, cfg)
+	}
+	if !cfg.Start.IsZero() {
+		t.Errorf("Expected Start to be reset, got %v", cfg.Start)
+	}
+	Reset[Config](nil)
+	Reset(new(int))
+}
+
+func TestScreamingSnake(t *testing.T) {
+	tests := map[string]string{
+		"maxConns":  "MAX_CONNS",
+		"MaxConns":  "MAX_CONNS",
+		"max-conns": "MAX_CONNS",
+		"log_level": "LOG_LEVEL",
+		"HTTPPort":  "HTTP_PORT",
+		"userID":    "USER_ID",
+		"retry2Max": "RETRY2_MAX",
+	}
+
+	for input, expected := range tests {
+		if result := screamingSnake(input); result != expected {
+			t.Errorf("screamingSnake(%q): expected %q, got %q", input, expected, result)
+		}
+	}
+}
+
+func TestMerge(t *testing.T) {
+	type Database struct {
+		Host string
+		Port int
+	}
+	type Config struct {
+		Name    string
+		Debug   bool
+		Timeout *int
+		Tags    []string
+		DB      Database
+	}
+
+	timeout := 30
+	base := Config{
+		Name:    "base",
+		Timeout: &timeout,
+		Tags:    []string{"a"},
+		DB:      Database{Host: "db.local", Port: 5432},
+	}
+	override := Config{
+		Debug: true,
+		DB:    Database{Port: 6432},
+	}
+
+	Merge(&base, &override)
+
+	expected := Config{
+		Name:    "base",
+		Debug:   true,
+		Timeout: &timeout,
+		Tags:    []string{"a"},
+		DB:      Database{Host: "db.local", Port: 6432},
+	}
+	if !reflect.DeepEqual(base, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, base)
+	}
+
+	Merge(nil, &base)
+	Merge(&base, nil)
+	if !reflect.DeepEqual(base, expected) {
+		t.Errorf("Expected nil arguments to leave %+v as is, got %+v", expected, base)
+	}
+}
+
+func TestEnvVars(t *testing.T) {
+	type Database struct {
+		Host string `env:"HOST" required:"true"`
+		Port int    `env:"PORT"`
+	}
+	type Config struct {
+		Name     string            `env:"NAME"`
+		Internal string            `env:"-"`
+		Extra    map[string]string `env:"*"`
+		Database Database          `envPrefix:"DB_"`
+	}
+
+	expected := []EnvVar{
+		{Name: "NAME"},
+		{Name: "DB_HOST", Required: true},
+		{Name: "DB_PORT"},
+	}
+	if vars := EnvVars(&Config{}); !reflect.DeepEqual(vars, expected) {
+		t.Errorf("Expected %v, got %v", expected, vars)
+	}
+
+	names := EnvNames(Config{})
+	if !reflect.DeepEqual(names, []string{"NAME", "DB_HOST", "DB_PORT"}) {
+		t.Errorf("Expected [NAME DB_HOST DB_PORT], got %v", names)
+	}
+	type Cache struct {
+		TTL int `json:"ttl"`
+	}
+	type Derived struct {
+		MaxConns int
+		Cache    Cache `envPrefix:"CACHE"`
+		Started  time.Time
+	}
+	opts := Options{GlobalPrefix: "APP_", PrefixSeparator: "_", DeriveNames: true, JSONTagFallback: true}
+	names = EnvNamesWithOptions(&Derived{}, opts)
+	if !reflect.DeepEqual(names, []string{"APP_MAX_CONNS", "APP_CACHE_TTL", "APP_STARTED"}) {
+		t.Errorf("Expected [APP_MAX_CONNS APP_CACHE_TTL APP_STARTED], got %v", names)
+	}
+
+	envVars := make(map[string]string, len(names))
+	for _, name := range names {
+		envVars[name] = "7"
+	}
+	envVars["APP_STARTED"] = "2024-01-02T03:04:05Z"
+	var cfg Derived
+	if err := parseEnv(&cfg, envVars, opts); err != nil {
+		t.Fatalf("parseEnv failed: %v", err)
+	}
+	if cfg.MaxConns != 7 || cfg.Cache.TTL != 7 || cfg.Started.IsZero() {
+		t.Errorf("Expected every listed name to be read, got %+v", cfg)
+	}
+}
+
+func TestDump(t *testing.T) {
+	type Database struct {
+		Host     string `env:"HOST"`
+		Password string `env:"PASSWORD" secret:"true"`
+	}
+	type Config struct {
+		Port     int      `env:"PORT"`
+		Token    *string  `env:"TOKEN" secret:"true"`
+		Database Database `envPrefix:"DB_"`
+	}
+
+	token := "abc"
+	cfg := Config{Port: 8080, Token: &token, Database: Database{Host: "db.local", Password: "hunter2hunter"}}
+
+	expected := "PORT=8080\nTOKEN=******\nDB_HOST=db.local\nDB_PASSWORD=******\n"
+	if got := Dump(&cfg, DumpOptions{}); got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+
+	expected = "PORT=8080\nTOKEN=****** (3 chars)\nDB_HOST=db.local\nDB_PASSWORD=****** (13 chars)\n"
+	got := Dump(cfg, DumpOptions{ShowSecretLength: true})
+	if got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+	if strings.Contains(got, "hunter2") || strings.Contains(got, "abc") {
+		t.Errorf("Expected secrets to be masked, got %q", got)
+	}
+}
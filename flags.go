@@ -0,0 +1,75 @@
+package environment
+
+import (
+	"flag"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// BindFlags registers a flag on fs for every env-tagged field of v, named
+// after its variable in lower case (DB_HOST becomes -db_host). Each flag
+// writes straight into its field, so calling BindFlags after loading and
+// then fs.Parse lets the command line override the environment. A flag's
+// default, as shown in usage, is the field's current value, or its
+// `default` tag while the field is zero; the field itself is left as it
+// is. v must be a non-nil pointer to a struct.
+func BindFlags(fs *flag.FlagSet, v any) error {
+	return BindFlagsWithOptions(fs, v, Options{})
+}
+
+// BindFlagsWithOptions is BindFlags with flag names derived from the
+// variable names under opts, and values parsed under opts.
+func BindFlagsWithOptions(fs *flag.FlagSet, v any, opts Options) error {
+	ptr := reflect.ValueOf(v)
+	if ptr.Kind() != reflect.Pointer || ptr.IsNil() || ptr.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("BindFlags requires a non-nil pointer to a struct, got %T", v)
+	}
+
+	b := &binder{opts: opts}
+	walkFields(ptr.Elem(), opts, func(field reflect.Value, structField reflect.StructField, name string) bool {
+		if !field.CanSet() || structField.Tag.Get("env") == catchAllTag {
+			return true
+		}
+		flagName := strings.ToLower(name)
+		fs.Var(&fieldFlag{b: b, field: field, structField: structField}, flagName, "sets "+name)
+		// The tag default is only shown: a zero field may have been set to
+		// zero on purpose, so it is never overwritten.
+		if def := structField.Tag.Get("default"); def != "" && field.IsZero() && structField.Tag.Get("secret") != "true" {
+			fs.Lookup(flagName).DefValue = def
+		}
+		return true
+	})
+	return nil
+}
+
+// fieldFlag is a flag.Value that parses into a struct field the same way
+// as its environment variable.
+type fieldFlag struct {
+	b           *binder
+	field       reflect.Value
+	structField reflect.StructField
+}
+
+func (f *fieldFlag) String() string {
+	// flag calls String on a zero fieldFlag to detect zero defaults.
+	if !f.field.IsValid() || f.structField.Tag.Get("secret") == "true" {
+		return ""
+	}
+	if f.field.Kind() == reflect.Pointer {
+		if f.field.IsNil() {
+			return ""
+		}
+		return fmt.Sprint(f.field.Elem().Interface())
+	}
+	return fmt.Sprint(f.field.Interface())
+}
+
+func (f *fieldFlag) Set(value string) error {
+	return f.b.setField(f.field, f.structField, value)
+}
+
+// IsBoolFlag lets bool fields be set with a bare -name.
+func (f *fieldFlag) IsBoolFlag() bool {
+	return f.field.IsValid() && f.field.Kind() == reflect.Bool
+}
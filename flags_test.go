@@ -0,0 +1,81 @@
+package environment
+
+import (
+	"flag"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestBindFlags(t *testing.T) {
+	type Database struct {
+		Host string `env:"HOST" default:"localhost"`
+	}
+	type Config struct {
+		Port     int           `env:"FLAG_PORT"`
+		Timeout  time.Duration `env:"FLAG_TIMEOUT" default:"5s"`
+		Debug    bool          `env:"FLAG_DEBUG"`
+		Database Database      `envPrefix:"FLAG_DB_"`
+	}
+
+	var cfg Config
+	if err := parseEnv(&cfg, map[string]string{"FLAG_PORT": "8080"}, Options{}); err != nil {
+		t.Fatalf("parseEnv failed: %v", err)
+	}
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	if err := BindFlags(fs, &cfg); err != nil {
+		t.Fatalf("BindFlags failed: %v", err)
+	}
+	if f := fs.Lookup("flag_port"); f == nil || f.DefValue != "8080" {
+		t.Errorf("Expected flag_port defaulting to the env value 8080, got %+v", f)
+	}
+
+	if err := fs.Parse([]string{"-flag_port=9090", "-flag_debug", "-flag_db_host", "db.local"}); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if cfg.Port != 9090 {
+		t.Errorf("Expected flag to override env with 9090, got %d", cfg.Port)
+	}
+	if !cfg.Debug {
+		t.Error("Expected -flag_debug to set Debug")
+	}
+	if cfg.Database.Host != "db.local" {
+		t.Errorf("Expected db.local, got %q", cfg.Database.Host)
+	}
+	if cfg.Timeout != 5*time.Second {
+		t.Errorf("Expected default 5s, got %v", cfg.Timeout)
+	}
+
+	if err := BindFlags(flag.NewFlagSet("test", flag.ContinueOnError), cfg); err == nil {
+		t.Error("Expected error for non-pointer")
+	}
+}
+
+func TestBindFlagsKeepsZeroValues(t *testing.T) {
+	type Config struct {
+		Debug bool `env:"PB_DEBUG" default:"true"`
+		Port  int  `env:"PB_PORT" default:"80"`
+	}
+
+	var cfg Config
+	if err := parseEnv(&cfg, map[string]string{"PB_DEBUG": "false", "PB_PORT": "0"}, Options{}); err != nil {
+		t.Fatalf("parseEnv failed: %v", err)
+	}
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	if err := BindFlags(fs, &cfg); err != nil {
+		t.Fatalf("BindFlags failed: %v", err)
+	}
+	if err := fs.Parse(nil); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if cfg.Debug || cfg.Port != 0 {
+		t.Errorf("Expected zero values from the environment to survive, got %+v", cfg)
+	}
+	if f := fs.Lookup("pb_port"); f == nil || f.DefValue != "80" {
+		t.Errorf("Expected pb_port to show default 80, got %+v", f)
+	}
+}
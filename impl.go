@@ -0,0 +1,67 @@
+package environment
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var (
+	implsMu sync.RWMutex
+	impls   = make(map[reflect.Type]map[string]func() any)
+)
+
+// RegisterImpl registers ctor as the implementation of the interface type
+// iface selected by key. A field of that interface type reads key from its
+// env variable, calls the matching ctor and binds the returned pointer to
+// struct with the field's `envPrefix`, so each implementation keeps its own
+// settings. Obtain iface with reflect.TypeOf((*Iface)(nil)).Elem().
+func RegisterImpl(iface reflect.Type, key string, ctor func() any) {
+	if iface.Kind() != reflect.Interface {
+		panic(fmt.Sprintf("environment: RegisterImpl requires an interface type, got %s", iface))
+	}
+
+	implsMu.Lock()
+	defer implsMu.Unlock()
+	if impls[iface] == nil {
+		impls[iface] = make(map[string]func() any)
+	}
+	impls[iface][key] = ctor
+}
+
+func hasImpls(iface reflect.Type) bool {
+	implsMu.RLock()
+	defer implsMu.RUnlock()
+	return len(impls[iface]) > 0
+}
+
+// parseImpl sets the interface field to the implementation registered
+// under key, bound from the variables under prefix.
+func (b *binder) parseImpl(field reflect.Value, key, prefix string) error {
+	implsMu.RLock()
+	ctor, ok := impls[field.Type()][key]
+	keys := make([]string, 0, len(impls[field.Type()]))
+	for k := range impls[field.Type()] {
+		keys = append(keys, k)
+	}
+	implsMu.RUnlock()
+	if !ok {
+		sort.Strings(keys)
+		return fmt.Errorf("unknown implementation %q, expected one of %s", key, strings.Join(keys, ", "))
+	}
+
+	impl := reflect.ValueOf(ctor())
+	if impl.Kind() != reflect.Pointer || impl.IsNil() || impl.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("implementation %q must be a non-nil pointer to a struct, got %s", key, impl.Type())
+	}
+	if !impl.Type().Implements(field.Type()) {
+		return fmt.Errorf("implementation %q: %s does not implement %s", key, impl.Type(), field.Type())
+	}
+	if err := b.parseStruct(impl.Elem(), prefix); err != nil {
+		return err
+	}
+	field.Set(impl)
+	return nil
+}
@@ -0,0 +1,66 @@
+package environment
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type storage interface {
+	Location() string
+}
+
+type diskStorage struct {
+	Path string `env:"PATH" default:"/var/lib/app"`
+}
+
+func (s *diskStorage) Location() string { return s.Path }
+
+type bucketStorage struct {
+	Bucket string `env:"BUCKET" required:"true"`
+	Region string `env:"REGION" default:"us-east-1"`
+}
+
+func (s *bucketStorage) Location() string { return s.Region + "/" + s.Bucket }
+
+func TestRegisterImpl(t *testing.T) {
+	iface := reflect.TypeOf((*storage)(nil)).Elem()
+	RegisterImpl(iface, "disk", func() any { return &diskStorage{} })
+	RegisterImpl(iface, "bucket", func() any { return &bucketStorage{} })
+
+	type Config struct {
+		Storage storage `env:"STORAGE" envPrefix:"STORAGE_"`
+	}
+
+	tests := []struct {
+		name     string
+		envVars  map[string]string
+		expected string
+	}{
+		{"disk", map[string]string{"STORAGE": "disk"}, "/var/lib/app"},
+		{"bucket", map[string]string{"STORAGE": "bucket", "STORAGE_BUCKET": "assets"}, "us-east-1/assets"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cfg Config
+			if err := parseEnv(&cfg, tt.envVars, Options{}); err != nil {
+				t.Fatalf("parseEnv failed: %v", err)
+			}
+			if cfg.Storage == nil || cfg.Storage.Location() != tt.expected {
+				t.Errorf("Expected %s, got %v", tt.expected, cfg.Storage)
+			}
+		})
+	}
+
+	var cfg Config
+	err := parseEnv(&cfg, map[string]string{"STORAGE": "tape"}, Options{})
+	if err == nil || !strings.Contains(err.Error(), "expected one of bucket, disk") {
+		t.Errorf("Expected unknown implementation error, got %v", err)
+	}
+
+	cfg = Config{}
+	if err := parseEnv(&cfg, map[string]string{}, Options{}); err != nil || cfg.Storage != nil {
+		t.Errorf("Expected nil Storage without a discriminator, got %v, %v", cfg.Storage, err)
+	}
+}
@@ -0,0 +1,119 @@
+package environment
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// maxIndex bounds the indexes parseIndexed accepts, so that a stray key
+// such as SERVER_4000000000_HOST cannot allocate a huge slice.
+const maxIndex = 10000
+
+// parseIndexed fills a slice of structs from keys of the form
+// NAME_<index>_FIELD, growing it to cover the highest index present.
+// Slices of other types are filled by parseIndexedValues.
+func (b *binder) parseIndexed(field reflect.Value, name string) error {
+	elemType := field.Type().Elem()
+	if elemType.Kind() != reflect.Struct || isScalarStruct(reflect.New(elemType).Elem()) {
+		return b.parseIndexedValues(field, name)
+	}
+
+	seen := b.indexes(name + "_")
+	if len(seen) == 0 {
+		return nil
+	}
+
+	highest := 0
+	for index := range seen {
+		highest = max(highest, index)
+	}
+	if highest > maxIndex {
+		return fmt.Errorf("index %d of %s exceeds the limit of %d", highest, name, maxIndex)
+	}
+	if !b.opts.AllowIndexGaps && len(seen) != highest+1 {
+		for i := 0; ; i++ {
+			if !seen[i] {
+				return fmt.Errorf("missing index %d of %s", i, name)
+			}
+		}
+	}
+
+	slice := reflect.MakeSlice(field.Type(), highest+1, highest+1)
+	for i := 0; i <= highest; i++ {
+		if !seen[i] {
+			continue
+		}
+
+		if err := b.parseStruct(slice.Index(i), fmt.Sprintf("%s_%d_", name, i)); err != nil {
+			return err
+		}
+	}
+	field.Set(slice)
+	return nil
+}
+
+// parseIndexedValues fills a slice from NAME_0, NAME_1 and so on, for
+// systems that cannot emit comma-separated lists. It stops at the first
+// missing index.
+func (b *binder) parseIndexedValues(field reflect.Value, name string) error {
+	slice := reflect.MakeSlice(field.Type(), 0, 0)
+	for i := 0; ; i++ {
+		value, exists := b.lookup(fmt.Sprintf("%s_%d", name, i))
+		if !exists {
+			break
+		}
+		elem := reflect.New(field.Type().Elem()).Elem()
+		if err := setValue(elem, value); err != nil {
+			return fmt.Errorf("element %d: %v", i, err)
+		}
+		slice = reflect.Append(slice, elem)
+	}
+	if slice.Len() > 0 {
+		field.Set(slice)
+	}
+	return nil
+}
+
+// indexes collects the indexes N of every known key starting with
+// prefix followed by N and an underscore.
+func (b *binder) indexes(prefix string) map[int]bool {
+	seen := make(map[int]bool)
+	for _, key := range b.keys() {
+		rest, ok := strings.CutPrefix(key, prefix)
+		if !ok {
+			continue
+		}
+		digits, _, ok := strings.Cut(rest, "_")
+		if !ok {
+			continue
+		}
+		if index, err := strconv.Atoi(digits); err == nil && index >= 0 {
+			seen[index] = true
+		}
+	}
+	return seen
+}
+
+// keys lists every variable name visible to the binder, from files first
+// and then the process environment, with and without Options.OSPrefix.
+func (b *binder) keys() []string {
+	keys := make([]string, 0, len(b.envVars))
+	for key := range b.envVars {
+		keys = append(keys, key)
+	}
+	for _, kv := range os.Environ() {
+		if key, _, ok := strings.Cut(kv, "="); ok {
+			keys = append(keys, key)
+			if b.opts.OSPrefix == "" {
+				continue
+			}
+			if stripped, ok := strings.CutPrefix(key, b.opts.OSPrefix); ok {
+				keys = append(keys, stripped)
+			}
+		}
+	}
+	return keys
+}
@@ -0,0 +1,89 @@
+package environment
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseEnvIndexedStructs(t *testing.T) {
+	type Server struct {
+		Host string `env:"HOST"`
+		Port int    `env:"PORT"`
+	}
+	type Config struct {
+		Servers []Server `env:"SERVER" indexed:"true"`
+	}
+
+	envVars := map[string]string{
+		"SERVER_0_HOST": "a.local",
+		"SERVER_0_PORT": "80",
+		"SERVER_1_HOST": "b.local",
+		"SERVER_1_PORT": "81",
+	}
+
+	var cfg Config
+	if err := parseEnv(&cfg, envVars, Options{}); err != nil {
+		t.Fatalf("parseEnv failed: %v", err)
+	}
+	if len(cfg.Servers) != 2 {
+		t.Fatalf("Expected 2 servers, got %d", len(cfg.Servers))
+	}
+	if cfg.Servers[0] != (Server{"a.local", 80}) || cfg.Servers[1] != (Server{"b.local", 81}) {
+		t.Errorf("Unexpected servers %+v", cfg.Servers)
+	}
+
+	gapped := map[string]string{
+		"SERVER_0_HOST": "a.local",
+		"SERVER_2_HOST": "c.local",
+	}
+	if err := parseEnv(&cfg, gapped, Options{}); err == nil {
+		t.Error("Expected error for missing index")
+	}
+	if err := parseEnv(&cfg, gapped, Options{AllowIndexGaps: true}); err != nil {
+		t.Fatalf("parseEnv failed: %v", err)
+	}
+	if len(cfg.Servers) != 3 || cfg.Servers[1] != (Server{}) || cfg.Servers[2].Host != "c.local" {
+		t.Errorf("Unexpected servers %+v", cfg.Servers)
+	}
+	for _, key := range []string{"SERVER_9223372036854775807_HOST", "SERVER_4000000000_HOST"} {
+		for _, opts := range []Options{{}, {AllowIndexGaps: true}} {
+			err := parseEnv(&cfg, map[string]string{"SERVER_0_HOST": "a.local", key: "x"}, opts)
+			if err == nil {
+				t.Errorf("Expected error for %s with %+v", key, opts)
+			}
+		}
+	}
+}
+
+func TestParseEnvIndexedValues(t *testing.T) {
+	type Config struct {
+		Tags  []string `env:"TAG" indexed:"true"`
+		Ports []int    `env:"PORT" indexed:"true"`
+	}
+
+	envVars := map[string]string{
+		"TAG_0":  "alpha",
+		"TAG_1":  "beta, gamma",
+		"TAG_2":  "delta",
+		"TAG_4":  "skipped",
+		"PORT_0": "80",
+	}
+
+	var cfg Config
+	if err := parseEnv(&cfg, envVars, Options{}); err != nil {
+		t.Fatalf("parseEnv failed: %v", err)
+	}
+
+	if !reflect.DeepEqual(cfg.Tags, []string{"alpha", "beta, gamma", "delta"}) {
+		t.Errorf("Expected [alpha beta, gamma delta], got %q", cfg.Tags)
+	}
+	if !reflect.DeepEqual(cfg.Ports, []int{80}) {
+		t.Errorf("Expected [80], got %v", cfg.Ports)
+	}
+
+	err := parseEnv(&cfg, map[string]string{"PORT_0": "80", "PORT_1": "http"}, Options{})
+	if err == nil || !strings.Contains(err.Error(), "element 1") {
+		t.Errorf("Expected error naming element 1, got %v", err)
+	}
+}
@@ -0,0 +1,61 @@
+package environment
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// parseInline binds a struct field tagged `inline:"true"` from one value of
+// comma-separated key=value pairs, such as host=localhost,port=5432. Keys
+// match the nested fields' env names regardless of case; nested fields
+// left out fall back to their defaults.
+func (b *binder) parseInline(field reflect.Value, value string) error {
+	pairs := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		key, val, ok := strings.Cut(pair, "=")
+		if !ok {
+			return fmt.Errorf("invalid inline entry %q", pair)
+		}
+		pairs[strings.ToUpper(strings.TrimSpace(key))] = unquote(strings.TrimSpace(val))
+	}
+
+	typ := field.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		structField := typ.Field(i)
+		name := b.envName(structField)
+		if name == "" {
+			continue
+		}
+
+		key := strings.ToUpper(name)
+		val, ok := pairs[key]
+		delete(pairs, key)
+		if !ok {
+			if structField.Tag.Get("required") == "true" {
+				return fmt.Errorf("required key %s is missing", name)
+			}
+			if val = b.defaultValue(structField); val == "" {
+				continue
+			}
+		}
+		if err := b.setField(field.Field(i), structField, val); err != nil {
+			return redactSecret(structField, fmt.Errorf("invalid value for %s: %v", name, err))
+		}
+	}
+
+	if len(pairs) > 0 {
+		unknown := make([]string, 0, len(pairs))
+		for key := range pairs {
+			unknown = append(unknown, key)
+		}
+		sort.Strings(unknown)
+		return fmt.Errorf("unknown key %s", unknown[0])
+	}
+	return nil
+}
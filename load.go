@@ -0,0 +1,139 @@
+package environment
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"sort"
+)
+
+// LoadWithOptions fills instance from the given env files and the process
+// environment, returning an error instead of exiting on failure. Warnings
+// are returned rather than logged, so callers can report them without
+// failing.
+func LoadWithOptions[T any](instance *T, opts Options, paths ...string) ([]Warning, error) {
+	var warnings []Warning
+	opts.warnings = &warnings
+	err := fillSpecification(instance, opts, paths...)
+	return warnings, err
+}
+
+// LoadStdin fills instance from env-formatted input piped on standard
+// input, e.g. `generate-config | app`. It reads until EOF, so when stdin is
+// an interactive terminal it blocks until the stream is closed (Ctrl-D).
+func LoadStdin[T any](instance *T) error {
+	envVars, err := parseReader("stdin", os.Stdin, Options{})
+	if err != nil {
+		return fmt.Errorf("error loading stdin: %v", err)
+	}
+
+	if err := parseEnv(instance, envVars, Options{}); err != nil {
+		return fmt.Errorf("field load environment: %v", err)
+	}
+	return nil
+}
+
+// LoadEnvironment fills instance from .env, .env.<env> and .env.<env>.local
+// in that order, following the common dotenv layering convention. Files
+// that do not exist are skipped.
+func LoadEnvironment[T any](instance *T, env string) error {
+	candidates := []string{
+		defaultEnvironmentFile,
+		defaultEnvironmentFile + "." + env,
+		defaultEnvironmentFile + "." + env + ".local",
+	}
+
+	paths, err := existingFiles(candidates)
+	if err != nil {
+		return err
+	}
+	return fillSpecification(instance, Options{}, paths...)
+}
+
+// LoadProfile fills instance from .env and then .env.<profile>, where the
+// profile is named by APP_PROFILE or the variable in
+// Options.ProfileVariable, e.g. APP_PROFILE=prod layers .env.prod over
+// .env. Without a profile only .env is read. Files that do not exist are
+// skipped.
+func LoadProfile[T any](instance *T, opts Options) error {
+	candidates := []string{defaultEnvironmentFile}
+	name := opts.profileVariable()
+	profile, ok := opts.Overlay[name]
+	if !ok {
+		profile = os.Getenv(name)
+	}
+	if profile != "" {
+		candidates = append(candidates, defaultEnvironmentFile+"."+profile)
+	}
+
+	paths, err := existingFiles(candidates)
+	if err != nil {
+		return err
+	}
+	return fillSpecification(instance, opts, paths...)
+}
+
+// existingFiles returns the candidates that exist, in order.
+func existingFiles(candidates []string) ([]string, error) {
+	paths := make([]string, 0, len(candidates))
+	for _, path := range candidates {
+		if _, err := os.Stat(path); err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// Bind is the non-generic counterpart of LoadWithOptions for callers that
+// only hold an any, e.g. from a plugin registry. instance must be a
+// non-nil pointer to a struct.
+func Bind(instance any, paths ...string) error {
+	return fillSpecification(instance, Options{}, paths...)
+}
+
+// LoadFSGlob fills instance from every file in fsys matching pattern, such
+// as an embedded conf.d directory, loaded in sorted order so later names
+// override earlier ones. No matches is not an error.
+func LoadFSGlob[T any](instance *T, fsys fs.FS, pattern string) error {
+	matches, err := fs.Glob(fsys, pattern)
+	if err != nil {
+		return err
+	}
+	sort.Strings(matches)
+
+	envVars := make(map[string]string)
+	for _, name := range matches {
+		fileVars, err := loadFSFile(fsys, name)
+		if err != nil {
+			return fmt.Errorf("error loading .env file: %v", err)
+		}
+		for k, v := range fileVars {
+			envVars[k] = v
+		}
+	}
+
+	if err := parseEnv(instance, envVars, Options{}); err != nil {
+		return fmt.Errorf("field load environment: %v", err)
+	}
+	return nil
+}
+
+func loadFSFile(fsys fs.FS, name string) (map[string]string, error) {
+	file, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer func(file fs.File) {
+		if err := file.Close(); err != nil {
+			log.Fatalf("failed to close env file: %v", err)
+		}
+	}(file)
+
+	return parseReader(name, file, Options{})
+}
@@ -0,0 +1,431 @@
+package environment
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoadStdin(t *testing.T) {
+	type Config struct {
+		Host string `env:"STDIN_HOST"`
+		Port int    `env:"STDIN_PORT"`
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Failed to create pipe: %v", err)
+	}
+	stdin := os.Stdin
+	os.Stdin = r
+	defer func() { os.Stdin = stdin }()
+
+	if _, err := w.WriteString("STDIN_HOST=localhost\nSTDIN_PORT=8080\n"); err != nil {
+		t.Fatalf("Failed to write to pipe: %v", err)
+	}
+	w.Close()
+
+	var cfg Config
+	if err := LoadStdin(&cfg); err != nil {
+		t.Fatalf("LoadStdin failed: %v", err)
+	}
+	if cfg.Host != "localhost" || cfg.Port != 8080 {
+		t.Errorf("Expected localhost:8080, got %s:%d", cfg.Host, cfg.Port)
+	}
+}
+
+func TestLoadWithOptionsGlobalPrefix(t *testing.T) {
+	type Database struct {
+		Host string `env:"HOST"`
+	}
+	type Config struct {
+		Name string   `env:"NAME"`
+		DB   Database `envPrefix:"DB_"`
+	}
+
+	path := filepath.Join(t.TempDir(), ".env")
+	content := "APP_NAME=app\nAPP_DB_HOST=db.local\nNAME=unprefixed\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write env file: %v", err)
+	}
+
+	var cfg Config
+	if _, err := LoadWithOptions(&cfg, Options{GlobalPrefix: "APP_"}, path); err != nil {
+		t.Fatalf("LoadWithOptions failed: %v", err)
+	}
+	if cfg.Name != "app" || cfg.DB.Host != "db.local" {
+		t.Errorf("Expected app and db.local, got %q and %q", cfg.Name, cfg.DB.Host)
+	}
+}
+
+func TestLoadWithOptionsInvalidTarget(t *testing.T) {
+	type Config struct {
+		Name string `env:"NAME"`
+	}
+
+	if _, err := LoadWithOptions[Config](nil, Options{}); err == nil || !strings.Contains(err.Error(), "non-nil pointer to a struct") {
+		t.Errorf("Expected error for nil pointer, got %v", err)
+	}
+
+	var port int
+	if _, err := LoadWithOptions(&port, Options{}); err == nil || !strings.Contains(err.Error(), "*int") {
+		t.Errorf("Expected error for *int, got %v", err)
+	}
+}
+
+func TestLoadEnvironmentOverlays(t *testing.T) {
+	type Config struct {
+		Name  string `env:"OVERLAY_NAME"`
+		Debug bool   `env:"OVERLAY_DEBUG"`
+		Port  int    `env:"OVERLAY_PORT"`
+	}
+
+	t.Chdir(t.TempDir())
+	files := map[string]string{
+		".env":         "OVERLAY_NAME=base\nOVERLAY_DEBUG=false\nOVERLAY_PORT=80\n",
+		".env.staging": "OVERLAY_DEBUG=true\nOVERLAY_PORT=8080\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(name, []byte(content), 0o600); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	var cfg Config
+	if err := LoadEnvironment(&cfg, "staging"); err != nil {
+		t.Fatalf("LoadEnvironment failed: %v", err)
+	}
+	if cfg.Name != "base" || !cfg.Debug || cfg.Port != 8080 {
+		t.Errorf("Unexpected config %+v", cfg)
+	}
+}
+
+func TestBind(t *testing.T) {
+	type Config struct {
+		Name string `env:"BIND_NAME"`
+	}
+
+	path := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(path, []byte("BIND_NAME=plugin\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write env file: %v", err)
+	}
+
+	var instance any = &Config{}
+	if err := Bind(instance, path); err != nil {
+		t.Fatalf("Bind failed: %v", err)
+	}
+	if name := instance.(*Config).Name; name != "plugin" {
+		t.Errorf("Expected plugin, got %q", name)
+	}
+
+	if err := Bind(Config{}, path); err == nil {
+		t.Error("Expected error for non-pointer instance")
+	}
+}
+
+func TestLoadFSGlob(t *testing.T) {
+	type Config struct {
+		Name  string `env:"GLOB_NAME"`
+		Port  int    `env:"GLOB_PORT"`
+		Debug bool   `env:"GLOB_DEBUG" default:"true"`
+	}
+
+	fsys := fstest.MapFS{
+		"conf.d/10-base.env":     {Data: []byte("GLOB_NAME=base\nGLOB_PORT=80\n")},
+		"conf.d/20-override.env": {Data: []byte("GLOB_PORT=8080\n")},
+		"conf.d/README.md":       {Data: []byte("GLOB_NAME=ignored\n")},
+	}
+
+	var cfg Config
+	if err := LoadFSGlob(&cfg, fsys, "conf.d/*.env"); err != nil {
+		t.Fatalf("LoadFSGlob failed: %v", err)
+	}
+	if cfg.Name != "base" || cfg.Port != 8080 || !cfg.Debug {
+		t.Errorf("Unexpected config %+v", cfg)
+	}
+
+	var empty Config
+	if err := LoadFSGlob(&empty, fsys, "missing/*.env"); err != nil {
+		t.Fatalf("LoadFSGlob failed for no matches: %v", err)
+	}
+	if empty.Name != "" || !empty.Debug {
+		t.Errorf("Expected only defaults, got %+v", empty)
+	}
+}
+
+func TestLoadWithOptionsWarnings(t *testing.T) {
+	type Config struct {
+		URL  string `env:"WARN_DATABASE_URL" aliases:"WARN_DB_URL"`
+		Port int    `env:"WARN_PORT"`
+	}
+
+	path := filepath.Join(t.TempDir(), ".env")
+	content := "WARN_DB_URL=postgres://db\nWARN_PORT=80\nWARN_PORT=8080\nWARN_EXTRA=1\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write env file: %v", err)
+	}
+
+	logger := &recordingLogger{}
+	opts := Options{DuplicateKeys: DuplicateKeysWarn, WarnUnknownKeys: true, Logger: logger}
+
+	var cfg Config
+	warnings, err := LoadWithOptions(&cfg, opts, path)
+	if err != nil {
+		t.Fatalf("LoadWithOptions failed: %v", err)
+	}
+	if cfg.URL != "postgres://db" || cfg.Port != 8080 {
+		t.Errorf("Unexpected config %+v", cfg)
+	}
+
+	keys := make([]string, 0, len(warnings))
+	for _, warning := range warnings {
+		keys = append(keys, warning.Key)
+	}
+	if expected := []string{"WARN_PORT", "WARN_DB_URL", "WARN_EXTRA"}; !reflect.DeepEqual(keys, expected) {
+		t.Errorf("Expected warnings for %v, got %v", expected, warnings)
+	}
+	if len(logger.messages) != 0 {
+		t.Errorf("Expected returned warnings not to be logged, got %v", logger.messages)
+	}
+}
+
+func TestLoadWithOptionsOverlay(t *testing.T) {
+	type Config struct {
+		Port  int    `env:"OVERLAY_TEST_PORT" default:"80"`
+		Level string `env:"OVERLAY_TEST_LEVEL"`
+	}
+
+	path := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(path, []byte("OVERLAY_TEST_PORT=8080\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write env file: %v", err)
+	}
+	t.Setenv("OVERLAY_TEST_LEVEL", "info")
+
+	opts := Options{Overlay: map[string]string{
+		"OVERLAY_TEST_PORT":  "9090",
+		"OVERLAY_TEST_LEVEL": "debug",
+	}}
+
+	var cfg Config
+	if _, err := LoadWithOptions(&cfg, opts, path); err != nil {
+		t.Fatalf("LoadWithOptions failed: %v", err)
+	}
+	if cfg.Port != 9090 || cfg.Level != "debug" {
+		t.Errorf("Expected overlay values, got %+v", cfg)
+	}
+}
+
+func TestLoadWithOptionsRawValues(t *testing.T) {
+	type Config struct {
+		Template string `env:"RAW_TEMPLATE" raw:"true"`
+		Greeting string `env:"RAW_GREETING"`
+	}
+
+	path := filepath.Join(t.TempDir(), ".env")
+	content := "NAME=world\nRAW_TEMPLATE=\"hello ${NAME}\\n\"\nRAW_GREETING=\"hello ${NAME}\"\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write env file: %v", err)
+	}
+
+	var cfg Config
+	if _, err := LoadWithOptions(&cfg, Options{}, path); err != nil {
+		t.Fatalf("LoadWithOptions failed: %v", err)
+	}
+	if cfg.Template != `"hello ${NAME}\n"` {
+		t.Errorf("Expected raw value, got %q", cfg.Template)
+	}
+	if cfg.Greeting != "hello world" {
+		t.Errorf("Expected processed value, got %q", cfg.Greeting)
+	}
+
+	cfg = Config{}
+	if _, err := LoadWithOptions(&cfg, Options{RawValues: true}, path); err != nil {
+		t.Fatalf("LoadWithOptions failed: %v", err)
+	}
+	if cfg.Greeting != `"hello ${NAME}"` {
+		t.Errorf("Expected raw value with RawValues, got %q", cfg.Greeting)
+	}
+}
+
+func TestLoadWithOptionsInclude(t *testing.T) {
+	type Config struct {
+		Host    string `env:"INC_HOST"`
+		Port    int    `env:"INC_PORT"`
+		Timeout string `env:"INC_TIMEOUT"`
+	}
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"shared/base.env": "INC_HOST=base.local\nINC_PORT=80\nINC_TIMEOUT=5s\n",
+		"app.env":         "#include guards are described below\nINC_PORT=8080\n#include \"shared/base.env\"\nINC_TIMEOUT=10s\n",
+	}
+	if err := os.Mkdir(filepath.Join(dir, "shared"), 0o700); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	var cfg Config
+	if _, err := LoadWithOptions(&cfg, Options{}, filepath.Join(dir, "app.env")); err != nil {
+		t.Fatalf("LoadWithOptions failed: %v", err)
+	}
+
+	expected := Config{Host: "base.local", Port: 80, Timeout: "10s"}
+	if cfg != expected {
+		t.Errorf("Expected %+v, got %+v", expected, cfg)
+	}
+}
+
+func TestLoadWithOptionsIncludeCycle(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.env": "A=1\n#include \"b.env\"\n",
+		"b.env": "B=2\n#include \"a.env\"\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	var cfg struct{}
+	_, err := LoadWithOptions(&cfg, Options{}, filepath.Join(dir, "a.env"))
+	if err == nil || !strings.Contains(err.Error(), "include cycle") {
+		t.Errorf("Expected include cycle error, got %v", err)
+	}
+}
+
+func TestLoadProfile(t *testing.T) {
+	type Config struct {
+		Name string `env:"PROFILE_NAME"`
+		Port int    `env:"PROFILE_PORT"`
+	}
+
+	t.Chdir(t.TempDir())
+	files := map[string]string{
+		".env":         "PROFILE_NAME=base\nPROFILE_PORT=80\n",
+		".env.prod":    "PROFILE_PORT=443\n",
+		".env.staging": "PROFILE_NAME=staging\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(name, []byte(content), 0o600); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	tests := []struct {
+		profile  string
+		opts     Options
+		expected Config
+	}{
+		{"", Options{}, Config{Name: "base", Port: 80}},
+		{"prod", Options{}, Config{Name: "base", Port: 443}},
+		{"staging", Options{ProfileVariable: "DEPLOY_PROFILE"}, Config{Name: "staging", Port: 80}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.profile, func(t *testing.T) {
+			t.Setenv(tt.opts.profileVariable(), tt.profile)
+
+			var cfg Config
+			if err := LoadProfile(&cfg, tt.opts); err != nil {
+				t.Fatalf("LoadProfile failed: %v", err)
+			}
+			if cfg != tt.expected {
+				t.Errorf("Expected %+v, got %+v", tt.expected, cfg)
+			}
+		})
+	}
+}
+
+func TestRegisterEnvironmentOnFatal(t *testing.T) {
+	type Config struct {
+		Name string `env:"ON_FATAL_NAME"`
+	}
+
+	onFatal := OnFatal
+	t.Cleanup(func() { OnFatal = onFatal })
+
+	var got error
+	OnFatal = func(err error) { got = err }
+	t.Setenv(dotenvPathVariable, filepath.Join(t.TempDir(), "missing.env"))
+
+	var cfg Config
+	RegisterEnvironment(&cfg)
+	if got == nil || !strings.Contains(got.Error(), "missing.env does not exist") {
+		t.Errorf("Expected OnFatal to receive the load error, got %v", got)
+	}
+}
+
+func TestLoadWithOptionsProvenance(t *testing.T) {
+	type Config struct {
+		Host    string `env:"PROV_HOST"`
+		Port    int    `env:"PROV_PORT"`
+		User    string `env:"PROV_USER"`
+		Timeout string `env:"PROV_TIMEOUT" default:"5s"`
+		Unset   string `env:"PROV_UNSET"`
+	}
+
+	dir := t.TempDir()
+	base := filepath.Join(dir, "base.env")
+	local := filepath.Join(dir, "local.env")
+	if err := os.WriteFile(base, []byte("PROV_HOST=base\nPROV_PORT=80\nPROV_USER=base\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write env file: %v", err)
+	}
+	if err := os.WriteFile(local, []byte("PROV_PORT=8080\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write env file: %v", err)
+	}
+	t.Setenv("PROV_USER", "os")
+
+	var provenance Provenance
+	opts := Options{Overlay: map[string]string{"PROV_USER": "overlay"}, Provenance: &provenance}
+	var cfg Config
+	if _, err := LoadWithOptions(&cfg, opts, base, local); err != nil {
+		t.Fatalf("LoadWithOptions failed: %v", err)
+	}
+
+	tests := map[string]string{
+		"PROV_HOST":    base,
+		"PROV_PORT":    local,
+		"PROV_USER":    SourceOverlay,
+		"PROV_TIMEOUT": SourceDefault,
+		"PROV_UNSET":   "",
+	}
+	for name, expected := range tests {
+		if got := provenance.Source(name); got != expected {
+			t.Errorf("Expected %s to come from %q, got %q", name, expected, got)
+		}
+	}
+
+	t.Setenv("PROV_HOST_OS", "os")
+	type OSConfig struct {
+		Host string `env:"PROV_HOST_OS"`
+	}
+	var osCfg OSConfig
+	if _, err := LoadWithOptions(&osCfg, Options{Provenance: &provenance}); err != nil {
+		t.Fatalf("LoadWithOptions failed: %v", err)
+	}
+	if got := provenance.Source("PROV_HOST_OS"); got != SourceOSEnv {
+		t.Errorf("Expected %q, got %q", SourceOSEnv, got)
+	}
+	type FoldConfig struct {
+		Host string `env:"prov_fold_host"`
+	}
+	var foldCfg FoldConfig
+	foldFile := filepath.Join(dir, "fold.env")
+	if err := os.WriteFile(foldFile, []byte("PROV_FOLD_HOST=file\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write env file: %v", err)
+	}
+	if _, err := LoadWithOptions(&foldCfg, Options{CaseInsensitive: true, Provenance: &provenance}, foldFile); err != nil {
+		t.Fatalf("LoadWithOptions failed: %v", err)
+	}
+	if got := provenance.Source("prov_fold_host"); foldCfg.Host != "file" || got != foldFile {
+		t.Errorf("Expected file value from %q, got %q from %q", foldFile, foldCfg.Host, got)
+	}
+}
@@ -0,0 +1,228 @@
+package environment
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"sync/atomic"
+)
+
+const (
+	defaultModeVariable    = "APP_ENV"
+	defaultProfileVariable = "APP_PROFILE"
+	defaultEnvironmentFile = ".env"
+	// dotenvPathVariable names the variable that overrides which env file
+	// RegisterEnvironment loads.
+	dotenvPathVariable = "DOTENV_PATH"
+)
+
+// DuplicateKeyPolicy decides what happens when a single file defines the
+// same key more than once. Overrides across files are always allowed.
+type DuplicateKeyPolicy int
+
+const (
+	// DuplicateKeysAllow lets the later definition win silently.
+	DuplicateKeysAllow DuplicateKeyPolicy = iota
+	// DuplicateKeysWarn lets the later definition win and logs a warning.
+	DuplicateKeysWarn
+	// DuplicateKeysError aborts the load.
+	DuplicateKeysError
+)
+
+// Logger receives warnings emitted while loading configuration.
+// *log.Logger satisfies it.
+type Logger interface {
+	Printf(format string, v ...any)
+}
+
+// Options controls how environment files are loaded and bound to a struct.
+type Options struct {
+	// Overlay supplies values that take precedence over everything else,
+	// e.g. from command-line flags. Lookups go overlay, then files, then
+	// the process environment, then `default` tags.
+	Overlay map[string]string
+	// ModeVariable names the variable whose value selects mode-specific
+	// defaults, e.g. `default_prod:"..."` when it is set to "prod".
+	// Defaults to APP_ENV.
+	ModeVariable string
+	// ProfileVariable names the variable whose value selects the
+	// .env.<profile> file layered by LoadProfile. Defaults to APP_PROFILE.
+	ProfileVariable string
+	// GlobalPrefix is prepended to every variable name the struct reads,
+	// ahead of any `envPrefix` tags, e.g. "APP_" turns DB_HOST into
+	// APP_DB_HOST.
+	GlobalPrefix string
+	// PrefixSeparator is placed between an `envPrefix` tag and the names
+	// it prefixes. It defaults to "", so `envPrefix:"DB_"` yields DB_HOST,
+	// while "_" lets `envPrefix:"DB"` yield the same name.
+	PrefixSeparator string
+	// AllowIndexGaps leaves missing entries of `indexed` slices at their
+	// zero value instead of failing, e.g. SERVER_0_* and SERVER_2_* without
+	// SERVER_1_*.
+	AllowIndexGaps bool
+	// Sections makes INI-style [name] headers prefix the keys that follow
+	// with NAME_ until the next header. Headers are ignored otherwise.
+	Sections bool
+	// SemicolonComments treats lines starting with ; and unquoted ; after
+	// whitespace as comments, as in INI files. # is always a comment.
+	SemicolonComments bool
+	// NoLineContinuation disables joining lines that end in a backslash,
+	// so values such as DIR=C:\Data\ are kept as written.
+	NoLineContinuation bool
+	// KeyPattern replaces the POSIX rule for variable names in env files,
+	// for key schemes that allow names such as 1KEY. Anchor it with ^ and $
+	// so that it checks the whole name, including any [section] prefix.
+	KeyPattern *regexp.Regexp
+	// RawValues stores every file value exactly as written after the =,
+	// skipping quote removal, escape decoding and ${VAR} expansion. The
+	// `raw:"true"` tag does the same for a single field.
+	RawValues bool
+	// StrictExpansion fails the load when a ${VAR} reference cannot be
+	// resolved instead of keeping it literally.
+	StrictExpansion bool
+	// JSONTagFallback derives the variable name of fields without an `env`
+	// tag from their `json` tag, e.g. `json:"maxConns"` reads MAX_CONNS.
+	JSONTagFallback bool
+	// NoOSExpansion resolves ${VAR} references only against variables
+	// defined in the loaded files, never the process environment, so that
+	// builds are reproducible.
+	NoOSExpansion bool
+	// ThousandsSeparator is stripped from integer values, including the
+	// elements of integer slices, before parsing, so "." accepts 1.000.000
+	// and "," accepts 1,000,000. Slices are split first, so pick a `sep`
+	// that differs from it. Empty means strict.
+	ThousandsSeparator string
+	// LenientBools lets bool fields accept any integer, non-zero meaning
+	// true, in addition to the usual true/false forms.
+	LenientBools bool
+	// OSPrefix is stripped from process environment names before they are
+	// matched, for platforms that prefix every variable, e.g. "VCAP_" lets
+	// VCAP_DB_HOST supply DB_HOST. The unprefixed name still works but
+	// loses to the prefixed one. File variables are unaffected.
+	OSPrefix string
+	// CaseInsensitive falls back to matching variable names regardless of
+	// case, so `env:"db_host"` can read DB_HOST. Exact matches still win.
+	// On Windows the process environment is always matched this way.
+	CaseInsensitive bool
+	// DeriveNames reads exported fields without an `env` tag from their
+	// Go name in SCREAMING_SNAKE_CASE, e.g. MaxConns from MAX_CONNS.
+	// Tag a field `env:"-"` to skip it.
+	DeriveNames bool
+	// DuplicateKeys controls duplicate keys within a single file.
+	DuplicateKeys DuplicateKeyPolicy
+	// ForbidRequiredDefault rejects fields tagged both `required:"true"`
+	// and with a `default` or `default_<mode>` tag, which is a contradiction
+	// in the struct definition.
+	ForbidRequiredDefault bool
+	// CatchAllUnused limits fields tagged env:"*" to the variables that no
+	// other field reads.
+	CatchAllUnused bool
+	// WarnUnknownKeys warns about file variables that no field reads.
+	WarnUnknownKeys bool
+	// CheckDefaults parses every field's default tag, even when the
+	// variable is set, so a default that does not fit the field type is
+	// reported on the first load rather than when the variable goes away.
+	CheckDefaults bool
+	// AdditionalRequired lists variable names that must be set for this
+	// load, on top of fields tagged `required:"true"`. A default tag does
+	// not satisfy them.
+	AdditionalRequired []string
+	// Provenance, when set, records where the value of every variable
+	// the load read came from.
+	Provenance *Provenance
+	// Logger receives warnings. Defaults to the standard logger.
+	// LoadWithOptions returns warnings instead of logging them.
+	Logger Logger
+
+	warnings *[]Warning
+	// rawValues records file values as written, for fields tagged
+	// `raw:"true"`.
+	rawValues map[string]string
+	// origins maps each file variable to the file that last set it.
+	origins map[string]string
+	// including holds the files being scanned through #include
+	// directives, to detect cycles.
+	including map[string]bool
+	// base holds values from structured config files such as TOML, which
+	// the environment overrides.
+	base map[string]string
+}
+
+// Warning is a non-fatal problem found while loading, such as a
+// deprecated alias or a duplicate key.
+type Warning struct {
+	// Key is the variable the warning is about.
+	Key     string
+	Message string
+}
+
+func (w Warning) String() string {
+	return w.Message
+}
+
+func (o Options) modeVariable() string {
+	if o.ModeVariable == "" {
+		return defaultModeVariable
+	}
+	return o.ModeVariable
+}
+
+func (o Options) profileVariable() string {
+	if o.ProfileVariable == "" {
+		return defaultProfileVariable
+	}
+	return o.ProfileVariable
+}
+
+func (o Options) keyPattern() *regexp.Regexp {
+	if o.KeyPattern != nil {
+		return o.KeyPattern
+	}
+	return validEnvVarRegex
+}
+
+func (o Options) commentMarkers() string {
+	if o.SemicolonComments {
+		return "#;"
+	}
+	return "#"
+}
+
+// warn collects a warning when the caller asked for them to be returned,
+// and logs it otherwise.
+func (o Options) warn(key, format string, v ...any) {
+	message := fmt.Sprintf(format, v...)
+	if o.warnings != nil {
+		*o.warnings = append(*o.warnings, Warning{Key: key, Message: message})
+		return
+	}
+	o.logf("environment: %s", message)
+}
+
+// OnFatal handles the error when RegisterEnvironment cannot load the
+// configuration. It logs the error and exits by default; replace it to
+// panic, record a metric or shut down cleanly instead.
+var OnFatal = func(err error) {
+	log.Fatal(err)
+}
+
+// quiet suppresses logged warnings; see SetQuiet.
+var quiet atomic.Bool
+
+// SetQuiet turns off the warnings the package would otherwise log, for
+// CLIs that need clean output. Warnings returned by LoadWithOptions are
+// unaffected, and fatal errors are still reported before exiting.
+func SetQuiet(q bool) {
+	quiet.Store(q)
+}
+
+func (o Options) logf(format string, v ...any) {
+	if quiet.Load() {
+		return
+	}
+	if o.Logger == nil {
+		log.Printf(format, v...)
+		return
+	}
+	o.Logger.Printf(format, v...)
+}
@@ -0,0 +1,162 @@
+package environment
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ParseTyped loads paths into a flat map without binding to a struct,
+// inferring each value's type: int64, float64, bool for "true"/"false",
+// and string otherwise.
+func ParseTyped(paths ...string) (map[string]any, error) {
+	envVars, err := loadFiles(Options{}, paths...)
+	if err != nil {
+		return nil, err
+	}
+
+	typed := make(map[string]any, len(envVars))
+	for key, value := range envVars {
+		typed[key] = inferType(value)
+	}
+	return typed, nil
+}
+
+func inferType(value string) any {
+	if intVal, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return intVal
+	}
+	if floatVal, err := strconv.ParseFloat(value, 64); err == nil && !math.IsInf(floatVal, 0) && !math.IsNaN(floatVal) {
+		return floatVal
+	}
+	if strings.EqualFold(value, "true") {
+		return true
+	}
+	if strings.EqualFold(value, "false") {
+		return false
+	}
+	return value
+}
+
+// ParseFunc streams the env file at path, calling fn with each key and its
+// value after escape processing and expansion, and stops at the first error
+// fn returns. No result map is built for the caller; values are only kept
+// so that later lines can reference them.
+func ParseFunc(path string, fn func(key, value string) error) error {
+	return scanFile(path, make(map[string]string), fn)
+}
+
+// ParseInto parses paths in order and stores their variables in dst,
+// overwriting keys that are already present. ${VAR} references can see
+// the keys dst held beforehand, so callers can layer sources their own way.
+func ParseInto(dst map[string]string, paths ...string) error {
+	for _, path := range paths {
+		if err := scanFile(path, dst, nil); err != nil {
+			return fmt.Errorf("error loading .env file: %v", err)
+		}
+	}
+	return nil
+}
+
+// scanFile runs scanEnv over the file at path.
+func scanFile(path string, envVars map[string]string, fn func(key, value string) error) error {
+	file, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return err
+	}
+	defer func(file *os.File) {
+		if err := file.Close(); err != nil {
+			log.Fatalf("failed to close env file: %v", err)
+		}
+	}(file)
+
+	return scanEnv(path, file, Options{}, envVars, fn)
+}
+
+// ParseReaderFormat parses r as the given format, for streams without a
+// file extension to go by. "env" is the .env syntax; "json" and "toml"
+// are flattened like TOML files, so {"database": {"host": "x"}} yields
+// DATABASE_HOST. YAML is not supported, since it would need a dependency.
+func ParseReaderFormat(r io.Reader, format string) (map[string]string, error) {
+	switch strings.ToLower(format) {
+	case "env", "dotenv":
+		return parseReader(format, r, Options{})
+	case "json":
+		return parseJSON(r)
+	case "toml":
+		return parseTOML(format, r)
+	case "yaml", "yml":
+		return nil, fmt.Errorf("format %s is not supported", format)
+	default:
+		return nil, fmt.Errorf("unknown format %q", format)
+	}
+}
+
+// parseJSON flattens a JSON object into variables. Arrays of scalars are
+// joined with commas; arrays holding objects or arrays are kept as JSON.
+func parseJSON(r io.Reader) (map[string]string, error) {
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+	var doc map[string]any
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %v", err)
+	}
+
+	vars := make(map[string]string)
+	if err := flattenJSON(vars, "", doc); err != nil {
+		return nil, err
+	}
+	return vars, nil
+}
+
+func flattenJSON(vars map[string]string, prefix string, obj map[string]any) error {
+	for key, value := range obj {
+		name := prefix + tomlKey(key)
+		switch v := value.(type) {
+		case map[string]any:
+			if err := flattenJSON(vars, name+"_", v); err != nil {
+				return err
+			}
+		case []any:
+			if hasComposite(v) {
+				raw, err := json.Marshal(v)
+				if err != nil {
+					return err
+				}
+				vars[name] = string(raw)
+				continue
+			}
+			elems := make([]string, len(v))
+			for i, elem := range v {
+				elems[i] = jsonScalar(elem)
+			}
+			vars[name] = strings.Join(elems, ",")
+		default:
+			vars[name] = jsonScalar(v)
+		}
+	}
+	return nil
+}
+
+func hasComposite(values []any) bool {
+	for _, value := range values {
+		switch value.(type) {
+		case map[string]any, []any:
+			return true
+		}
+	}
+	return false
+}
+
+func jsonScalar(value any) string {
+	if value == nil {
+		return ""
+	}
+	return fmt.Sprint(value)
+}
@@ -0,0 +1,144 @@
+package environment
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseTyped(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".env")
+	content := "PORT=8080\nRATIO=0.75\nDEBUG=true\nQUIET=FALSE\nNAME=app\nLIMIT=inf\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write env file: %v", err)
+	}
+
+	typed, err := ParseTyped(path)
+	if err != nil {
+		t.Fatalf("ParseTyped failed: %v", err)
+	}
+
+	expected := map[string]any{
+		"PORT":  int64(8080),
+		"RATIO": 0.75,
+		"DEBUG": true,
+		"QUIET": false,
+		"NAME":  "app",
+		"LIMIT": "inf",
+	}
+	if !reflect.DeepEqual(typed, expected) {
+		t.Errorf("Expected %v, got %v", expected, typed)
+	}
+}
+
+func TestParseFunc(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".env")
+	content := "HOST=localhost\nPORT=8080\nURL=http://${HOST}:${PORT}\nSTOP=here\nAFTER=skipped\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write env file: %v", err)
+	}
+
+	var keys []string
+	values := make(map[string]string)
+	errStop := errors.New("stop")
+	err := ParseFunc(path, func(key, value string) error {
+		keys = append(keys, key)
+		values[key] = value
+		if key == "STOP" {
+			return errStop
+		}
+		return nil
+	})
+	if !errors.Is(err, errStop) {
+		t.Fatalf("Expected callback error, got %v", err)
+	}
+	if len(keys) != 4 {
+		t.Errorf("Expected 4 invoked keys, got %v", keys)
+	}
+	if values["URL"] != "http://localhost:8080" {
+		t.Errorf("Expected expanded URL, got %q", values["URL"])
+	}
+}
+
+func TestParseInto(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".env")
+	content := "HOST=db.local\nURL=postgres://${USER_NAME}@${HOST}\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write env file: %v", err)
+	}
+
+	dst := map[string]string{"USER_NAME": "admin", "HOST": "localhost", "PORT": "5432"}
+	if err := ParseInto(dst, path); err != nil {
+		t.Fatalf("ParseInto failed: %v", err)
+	}
+
+	expected := map[string]string{
+		"USER_NAME": "admin",
+		"HOST":      "db.local",
+		"PORT":      "5432",
+		"URL":       "postgres://admin@db.local",
+	}
+	if !reflect.DeepEqual(dst, expected) {
+		t.Errorf("Expected %v, got %v", expected, dst)
+	}
+
+	if err := ParseInto(dst, filepath.Join(t.TempDir(), "missing.env")); err == nil {
+		t.Error("Expected error for missing file")
+	}
+}
+
+func TestParseReaderFormat(t *testing.T) {
+	tests := []struct {
+		name     string
+		format   string
+		input    string
+		expected map[string]string
+	}{
+		{
+			name:     "env",
+			format:   "env",
+			input:    "HOST=localhost\nURL=http://${HOST}\n",
+			expected: map[string]string{"HOST": "localhost", "URL": "http://localhost"},
+		},
+		{
+			name:   "json",
+			format: "json",
+			input:  `{"port": 8080, "debug": true, "database": {"host": "db.local", "replicas": ["a", "b"]}, "routes": [{"path": "/"}], "token": null}`,
+			expected: map[string]string{
+				"PORT":              "8080",
+				"DEBUG":             "true",
+				"DATABASE_HOST":     "db.local",
+				"DATABASE_REPLICAS": "a,b",
+				"ROUTES":            `[{"path":"/"}]`,
+				"TOKEN":             "",
+			},
+		},
+		{
+			name:     "toml",
+			format:   "TOML",
+			input:    "[server]\nport = 8080\n",
+			expected: map[string]string{"SERVER_PORT": "8080"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseReaderFormat(strings.NewReader(tt.input), tt.format)
+			if err != nil {
+				t.Fatalf("ParseReaderFormat failed: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+
+	for _, format := range []string{"yaml", "ini"} {
+		if _, err := ParseReaderFormat(strings.NewReader(""), format); err == nil {
+			t.Errorf("Expected error for format %s", format)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package environment
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+var (
+	parsersMu sync.RWMutex
+	parsers   = make(map[string]func(value string) (any, error))
+	enums     = make(map[reflect.Type]func(value string) (reflect.Value, bool))
+)
+
+// RegisterParser makes fn available to fields tagged `parser:"<name>"` and
+// to slice fields tagged `elem_parser:"<name>"`, which convert each element
+// with it. fn must return a value assignable or convertible to the field
+// or element type. Registering a name again replaces the earlier parser.
+func RegisterParser(name string, fn func(value string) (any, error)) {
+	parsersMu.Lock()
+	defer parsersMu.Unlock()
+	parsers[name] = fn
+}
+
+func lookupParser(name string) (func(value string) (any, error), error) {
+	parsersMu.RLock()
+	defer parsersMu.RUnlock()
+	fn, ok := parsers[name]
+	if !ok {
+		return nil, fmt.Errorf("no parser registered as %q", name)
+	}
+	return fn, nil
+}
+
+// setParsed sets field to the result of the parser registered as name.
+func setParsed(field reflect.Value, value, name string) error {
+	fn, err := lookupParser(name)
+	if err != nil {
+		return err
+	}
+	parsed, err := fn(value)
+	if err != nil {
+		return err
+	}
+
+	result := reflect.ValueOf(parsed)
+	switch {
+	case !result.IsValid():
+		field.Set(reflect.Zero(field.Type()))
+	case result.Type().AssignableTo(field.Type()):
+		field.Set(result)
+	case result.Type().ConvertibleTo(field.Type()):
+		field.Set(result.Convert(field.Type()))
+	default:
+		return fmt.Errorf("parser %q returned %s, want %s", name, result.Type(), field.Type())
+	}
+	return nil
+}
+
+// RegisterEnum makes every field of type T, including slice elements and
+// pointers, parse its value with parse, typically a lookup in a map from
+// names to constants. A value parse rejects fails the load. Registering T
+// again replaces the earlier function.
+func RegisterEnum[T any](parse func(value string) (T, bool)) {
+	parsersMu.Lock()
+	defer parsersMu.Unlock()
+	enums[reflect.TypeFor[T]()] = func(value string) (reflect.Value, bool) {
+		v, ok := parse(value)
+		return reflect.ValueOf(&v).Elem(), ok
+	}
+}
+
+// setEnum sets field with the parser registered for its type, reporting
+// whether there is one.
+func setEnum(field reflect.Value, value string) (bool, error) {
+	parsersMu.RLock()
+	parse, ok := enums[field.Type()]
+	parsersMu.RUnlock()
+	if !ok {
+		return false, nil
+	}
+
+	v, ok := parse(value)
+	if !ok {
+		return true, fmt.Errorf("invalid value %q for %s", value, field.Type())
+	}
+	field.Set(v)
+	return true, nil
+}
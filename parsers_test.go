@@ -0,0 +1,116 @@
+package environment
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type hostPort struct {
+	Host string
+	Port string
+}
+
+func TestRegisterParserElements(t *testing.T) {
+	RegisterParser("hostport", func(value string) (any, error) {
+		host, port, ok := strings.Cut(value, ":")
+		if !ok {
+			return nil, fmt.Errorf("missing port in %q", value)
+		}
+		return hostPort{Host: host, Port: port}, nil
+	})
+	RegisterParser("upper", func(value string) (any, error) {
+		return strings.ToUpper(value), nil
+	})
+
+	type Level string
+	type Config struct {
+		Peers []hostPort `env:"PEERS" elem_parser:"hostport"`
+		Level Level      `env:"LEVEL" parser:"upper"`
+	}
+
+	envVars := map[string]string{"PEERS": "a.local:7000, b.local:7001", "LEVEL": "debug"}
+
+	var cfg Config
+	if err := parseEnv(&cfg, envVars, Options{}); err != nil {
+		t.Fatalf("parseEnv failed: %v", err)
+	}
+
+	expected := []hostPort{{"a.local", "7000"}, {"b.local", "7001"}}
+	if !reflect.DeepEqual(cfg.Peers, expected) {
+		t.Errorf("Expected %v, got %v", expected, cfg.Peers)
+	}
+	if cfg.Level != "DEBUG" {
+		t.Errorf("Expected DEBUG, got %q", cfg.Level)
+	}
+
+	err := parseEnv(&cfg, map[string]string{"PEERS": "a.local:7000,b.local"}, Options{})
+	if err == nil || !strings.Contains(err.Error(), "element 1") {
+		t.Errorf("Expected error naming element 1, got %v", err)
+	}
+}
+
+func TestRegisterParserUnknown(t *testing.T) {
+	type Config struct {
+		Peers []hostPort `env:"PEERS" elem_parser:"missing"`
+	}
+
+	var cfg Config
+	err := parseEnv(&cfg, map[string]string{"PEERS": "a.local:7000"}, Options{})
+	if err == nil || !strings.Contains(err.Error(), `no parser registered as "missing"`) {
+		t.Errorf("Expected unregistered parser error, got %v", err)
+	}
+}
+
+type logLevel int
+
+const (
+	levelDebug logLevel = iota
+	levelInfo
+	levelWarn
+)
+
+var logLevelNames = map[string]logLevel{"debug": levelDebug, "info": levelInfo, "warn": levelWarn}
+
+func (l logLevel) String() string {
+	for name, level := range logLevelNames {
+		if level == l {
+			return name
+		}
+	}
+	return fmt.Sprintf("logLevel(%d)", int(l))
+}
+
+func TestRegisterEnum(t *testing.T) {
+	RegisterEnum(func(value string) (logLevel, bool) {
+		level, ok := logLevelNames[strings.ToLower(value)]
+		return level, ok
+	})
+
+	type Config struct {
+		Level   logLevel   `env:"LEVEL" default:"info" oneof:"info warn"`
+		Verbose []logLevel `env:"VERBOSE"`
+	}
+
+	var cfg Config
+	if err := parseEnv(&cfg, map[string]string{"LEVEL": "WARN", "VERBOSE": "debug,info"}, Options{}); err != nil {
+		t.Fatalf("parseEnv failed: %v", err)
+	}
+	if cfg.Level != levelWarn {
+		t.Errorf("Expected warn, got %v", cfg.Level)
+	}
+	if !reflect.DeepEqual(cfg.Verbose, []logLevel{levelDebug, levelInfo}) {
+		t.Errorf("Expected [debug info], got %v", cfg.Verbose)
+	}
+
+	err := parseEnv(&cfg, map[string]string{"LEVEL": "trace"}, Options{})
+	if err == nil || !strings.Contains(err.Error(), `invalid value "trace"`) {
+		t.Errorf("Expected invalid value error, got %v", err)
+	}
+
+	err = parseEnv(&cfg, map[string]string{"LEVEL": "debug"}, Options{})
+	if err == nil || !strings.Contains(err.Error(), `"debug" is not one of info warn`) {
+		t.Errorf("Expected oneof error, got %v", err)
+	}
+}
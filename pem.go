@@ -0,0 +1,73 @@
+package environment
+
+import (
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"reflect"
+)
+
+var (
+	certificateType    = reflect.TypeOf(x509.Certificate{})
+	rsaPrivateKeyType  = reflect.TypeOf(rsa.PrivateKey{})
+	tlsCertificateType = reflect.TypeOf(tls.Certificate{})
+)
+
+// isPEMType reports whether typ is read from PEM-encoded key material.
+func isPEMType(typ reflect.Type) bool {
+	return typ == certificateType || typ == rsaPrivateKeyType || typ == tlsCertificateType
+}
+
+// setPEM parses PEM-encoded key material into an x509.Certificate, an
+// rsa.PrivateKey, or a tls.Certificate, whose value must hold both the
+// certificate chain and its private key.
+func setPEM(field reflect.Value, value string) error {
+	if field.Type() == tlsCertificateType {
+		cert, err := tls.X509KeyPair([]byte(value), []byte(value))
+		if err != nil {
+			return err
+		}
+		field.Set(reflect.ValueOf(cert))
+		return nil
+	}
+
+	block, _ := pem.Decode([]byte(value))
+	if block == nil {
+		return fmt.Errorf("no PEM data found")
+	}
+
+	switch field.Type() {
+	case certificateType:
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return err
+		}
+		field.Set(reflect.ValueOf(*cert))
+	case rsaPrivateKeyType:
+		key, err := parseRSAKey(block)
+		if err != nil {
+			return err
+		}
+		field.Set(reflect.ValueOf(*key))
+	}
+	return nil
+}
+
+// parseRSAKey accepts both PKCS #1 "RSA PRIVATE KEY" and PKCS #8
+// "PRIVATE KEY" blocks.
+func parseRSAKey(block *pem.Block) (*rsa.PrivateKey, error) {
+	if block.Type == "RSA PRIVATE KEY" {
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("PEM block holds a %T, not an RSA key", key)
+	}
+	return rsaKey, nil
+}
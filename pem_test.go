@@ -0,0 +1,73 @@
+package environment
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+)
+
+// selfSigned returns a PEM certificate for example.com and its PKCS #8 key.
+func selfSigned(t *testing.T) (certPEM, keyPEM string) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "example.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("Failed to marshal key: %v", err)
+	}
+	certPEM = string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+	keyPEM = string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}))
+	return certPEM, keyPEM
+}
+
+func TestParseEnvPEM(t *testing.T) {
+	type Config struct {
+		CA      *x509.Certificate `env:"TLS_CA"`
+		Key     *rsa.PrivateKey   `env:"TLS_KEY"`
+		KeyPair tls.Certificate   `env:"TLS_PAIR"`
+	}
+
+	certPEM, keyPEM := selfSigned(t)
+	envVars := map[string]string{
+		"TLS_CA":   certPEM,
+		"TLS_KEY":  keyPEM,
+		"TLS_PAIR": certPEM + keyPEM,
+	}
+
+	var cfg Config
+	if err := parseEnv(&cfg, envVars, Options{}); err != nil {
+		t.Fatalf("parseEnv failed: %v", err)
+	}
+
+	if cfg.CA == nil || cfg.CA.Subject.CommonName != "example.com" {
+		t.Errorf("Expected certificate for example.com, got %v", cfg.CA)
+	}
+	if cfg.Key == nil || !cfg.Key.PublicKey.Equal(cfg.CA.PublicKey) {
+		t.Error("Expected the private key matching the certificate")
+	}
+	if len(cfg.KeyPair.Certificate) != 1 || cfg.KeyPair.PrivateKey == nil {
+		t.Errorf("Expected a key pair with one certificate, got %+v", cfg.KeyPair)
+	}
+
+	if err := parseEnv(&cfg, map[string]string{"TLS_CA": "not a certificate"}, Options{}); err == nil {
+		t.Error("Expected error for a value without PEM data")
+	}
+}
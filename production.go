@@ -3,10 +3,19 @@
 
 package environment
 
-import "log"
+import "os"
 
 func RegisterEnvironment[T any](instance *T) {
-	if err := fillSpecification[T](instance); err != nil {
-		log.Fatalf("%v", err)
+	if err := fillSpecification(instance, Options{}, environmentFiles()...); err != nil {
+		OnFatal(err)
 	}
 }
+
+// environmentFiles returns the file named by DOTENV_PATH, if any; without
+// it only the process environment is used.
+func environmentFiles() []string {
+	if path := os.Getenv(dotenvPathVariable); path != "" {
+		return []string{path}
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+//go:build !development
+// +build !development
+
+package environment
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRegisterEnvironmentDotenvPath(t *testing.T) {
+	type Config struct {
+		Name string `env:"DOTENV_PATH_NAME"`
+	}
+
+	path := filepath.Join(t.TempDir(), "custom.env")
+	if err := os.WriteFile(path, []byte("DOTENV_PATH_NAME=from-file\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write env file: %v", err)
+	}
+
+	t.Setenv(dotenvPathVariable, "")
+	if files := environmentFiles(); len(files) != 0 {
+		t.Errorf("Expected no files without %s, got %v", dotenvPathVariable, files)
+	}
+
+	t.Setenv(dotenvPathVariable, path)
+	var cfg Config
+	RegisterEnvironment(&cfg)
+	if cfg.Name != "from-file" {
+		t.Errorf("Expected from-file, got %q", cfg.Name)
+	}
+}
@@ -0,0 +1,31 @@
+package environment
+
+// Sources reported by Provenance for values that did not come from a file.
+const (
+	SourceOverlay = "overlay"
+	SourceOSEnv   = "OS env"
+	SourceDefault = "default"
+)
+
+// Provenance records where each value of a load came from. Pass a pointer
+// in Options.Provenance and query it with Source once loading returns.
+type Provenance struct {
+	sources map[string]string
+}
+
+// Source returns the file path, SourceOSEnv, SourceOverlay or
+// SourceDefault for the variable envName, or "" if the load did not read
+// a value for it.
+func (p *Provenance) Source(envName string) string {
+	return p.sources[envName]
+}
+
+func (p *Provenance) record(envName, source string) {
+	if p == nil {
+		return
+	}
+	if p.sources == nil {
+		p.sources = make(map[string]string)
+	}
+	p.sources[envName] = source
+}
@@ -0,0 +1,56 @@
+package environment
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// fieldRef is a field whose `default:"@Other"` tag names a sibling field
+// to copy once every field has been bound.
+type fieldRef struct {
+	parent reflect.Value
+	index  int
+	ref    string
+}
+
+// resolveRefs copies referenced sibling values into fields that fell back
+// to an @ default. References may chain; each pass resolves the fields
+// whose target is no longer waiting itself, so cycles are reported rather
+// than looping.
+func (b *binder) resolveRefs() error {
+	pending := b.refs
+	for len(pending) > 0 {
+		var waiting []fieldRef
+		for _, r := range pending {
+			structField := r.parent.Type().Field(r.index)
+			target, ok := r.parent.Type().FieldByName(r.ref)
+			if !ok || len(target.Index) != 1 {
+				return fmt.Errorf("field %s: default references unknown field %s", structField.Name, r.ref)
+			}
+			if isPendingRef(pending, r.parent, target.Index[0]) {
+				waiting = append(waiting, r)
+				continue
+			}
+
+			field, value := r.parent.Field(r.index), r.parent.Field(target.Index[0])
+			if !value.Type().AssignableTo(field.Type()) {
+				return fmt.Errorf("field %s: cannot default %s from %s of type %s", structField.Name, field.Type(), r.ref, value.Type())
+			}
+			field.Set(value)
+		}
+		if len(waiting) == len(pending) {
+			return fmt.Errorf("field %s: circular default reference", pending[0].parent.Type().Field(pending[0].index).Name)
+		}
+		pending = waiting
+	}
+	return nil
+}
+
+func isPendingRef(refs []fieldRef, parent reflect.Value, index int) bool {
+	for _, r := range refs {
+		if r.index == index && r.parent.Type() == parent.Type() && r.parent.UnsafeAddr() == parent.UnsafeAddr() {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,62 @@
+package environment
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseEnvDefaultReference(t *testing.T) {
+	type Config struct {
+		AdvertiseHost string `env:"ADVERTISE_HOST" default:"@Host"`
+		Host          string `env:"HOST" default:"localhost"`
+		Schedule      string `env:"SCHEDULE" default:"@@daily"`
+	}
+
+	var cfg Config
+	if err := parseEnv(&cfg, map[string]string{"HOST": "10.0.0.1"}, Options{}); err != nil {
+		t.Fatalf("parseEnv failed: %v", err)
+	}
+	if cfg.AdvertiseHost != "10.0.0.1" {
+		t.Errorf("Expected AdvertiseHost to default to Host, got %q", cfg.AdvertiseHost)
+	}
+	if cfg.Schedule != "@daily" {
+		t.Errorf("Expected literal @daily, got %q", cfg.Schedule)
+	}
+
+	cfg = Config{}
+	envVars := map[string]string{"HOST": "10.0.0.1", "ADVERTISE_HOST": "public.example.com"}
+	if err := parseEnv(&cfg, envVars, Options{}); err != nil {
+		t.Fatalf("parseEnv failed: %v", err)
+	}
+	if cfg.AdvertiseHost != "public.example.com" {
+		t.Errorf("Expected explicit AdvertiseHost, got %q", cfg.AdvertiseHost)
+	}
+}
+
+func TestParseEnvDefaultReferenceErrors(t *testing.T) {
+	type Dangling struct {
+		Host string `env:"HOST" default:"@Hostname"`
+	}
+	type Circular struct {
+		A string `env:"A" default:"@B"`
+		B string `env:"B" default:"@A"`
+	}
+
+	tests := []struct {
+		name string
+		cfg  any
+		want string
+	}{
+		{"dangling", &Dangling{}, "unknown field Hostname"},
+		{"circular", &Circular{}, "circular"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := parseEnv(tt.cfg, map[string]string{}, Options{})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
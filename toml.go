@@ -0,0 +1,221 @@
+package environment
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// loadTOML reads a TOML file into flat variables: the key `host` under
+// `[database]` becomes DATABASE_HOST. Only single-line values are
+// supported: strings, numbers, booleans, dates and arrays of those, which
+// are joined with commas so they bind to slice fields.
+func loadTOML(filename string) (map[string]string, error) {
+	file, err := os.Open(filepath.Clean(filename))
+	if err != nil {
+		return nil, err
+	}
+	defer func(file *os.File) {
+		if err := file.Close(); err != nil {
+			log.Fatalf("failed to close env file: %v", err)
+		}
+	}(file)
+
+	return parseTOML(filename, file)
+}
+
+func parseTOML(name string, r io.Reader) (map[string]string, error) {
+	vars := make(map[string]string)
+	prefix := ""
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") {
+			table := strings.TrimSpace(stripTOMLComment(line))
+			if strings.HasPrefix(table, "[[") || !strings.HasSuffix(table, "]") {
+				return nil, fmt.Errorf("%s:%d: unsupported table header %q", name, lineNum, line)
+			}
+			prefix = tomlKey(table[1:len(table)-1]) + "_"
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("%s:%d: expected key = value", name, lineNum)
+		}
+		parsed, err := tomlValue(strings.TrimSpace(stripTOMLComment(value)))
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", name, lineNum, err)
+		}
+		vars[prefix+tomlKey(key)] = parsed
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return vars, nil
+}
+
+// tomlKey turns a possibly dotted TOML key into an env name.
+func tomlKey(key string) string {
+	parts := strings.Split(key, ".")
+	for i, part := range parts {
+		part = unquote(strings.TrimSpace(part))
+		parts[i] = strings.ToUpper(strings.ReplaceAll(part, "-", "_"))
+	}
+	return strings.Join(parts, "_")
+}
+
+func tomlValue(value string) (string, error) {
+	switch {
+	case value == "":
+		return "", fmt.Errorf("missing value")
+	case strings.HasPrefix(value, `"""`), strings.HasPrefix(value, "'''"):
+		return "", fmt.Errorf("multi-line strings are not supported")
+	case strings.HasPrefix(value, "{"):
+		return "", fmt.Errorf("inline tables are not supported")
+	case strings.HasPrefix(value, "["):
+		if !strings.HasSuffix(value, "]") {
+			return "", fmt.Errorf("multi-line arrays are not supported")
+		}
+		inner := strings.TrimSpace(value[1 : len(value)-1])
+		if inner == "" {
+			return "", nil
+		}
+		elems, err := splitTOMLArray(strings.TrimSuffix(inner, ","))
+		if err != nil {
+			return "", err
+		}
+		for i, elem := range elems {
+			parsed, err := tomlValue(strings.TrimSpace(elem))
+			if err != nil {
+				return "", err
+			}
+			elems[i] = parsed
+		}
+		return strings.Join(elems, ","), nil
+	case strings.HasPrefix(value, `"`):
+		if len(value) < 2 || !strings.HasSuffix(value, `"`) {
+			return "", fmt.Errorf("unterminated string %s", value)
+		}
+		return decodeEscapes(value[1 : len(value)-1])
+	case strings.HasPrefix(value, "'"):
+		if len(value) < 2 || !strings.HasSuffix(value, "'") {
+			return "", fmt.Errorf("unterminated string %s", value)
+		}
+		return value[1 : len(value)-1], nil
+	default:
+		return tomlBareValue(value)
+	}
+}
+
+// stripTOMLComment cuts value at the first # that is preceded by whitespace
+// and not inside a string. Unlike env values, bare TOML values never hold
+// quotes, so strings are tracked anywhere on the line, such as in arrays.
+func stripTOMLComment(value string) string {
+	var quote rune
+	escaped := false
+	for i, r := range value {
+		switch {
+		case escaped:
+			escaped = false
+		case quote != 0:
+			if r == '\\' && quote == '"' {
+				escaped = true
+			} else if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '#' && i > 0 && (value[i-1] == ' ' || value[i-1] == '\t'):
+			return value[:i]
+		}
+	}
+	return value
+}
+
+// splitTOMLArray splits the inside of a single-line array on the commas
+// that are not quoted, so ["a,b", "c"] has two elements.
+func splitTOMLArray(inner string) ([]string, error) {
+	var elems []string
+	var quote rune
+	escaped := false
+	start := 0
+	for i, r := range inner {
+		switch {
+		case escaped:
+			escaped = false
+		case quote != 0:
+			if r == '\\' && quote == '"' {
+				escaped = true
+			} else if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '[':
+			return nil, fmt.Errorf("nested arrays are not supported")
+		case r == ',':
+			elems = append(elems, inner[start:i])
+			start = i + 1
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated string in array [%s]", inner)
+	}
+	return append(elems, inner[start:]), nil
+}
+
+// tomlDateLayouts are the offset date-time, local date-time, local date and
+// local time forms of TOML dates.
+var tomlDateLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02",
+	"15:04:05.999999999",
+}
+
+// tomlBareValue checks that an unquoted value is a number, boolean or date.
+// Underscores between digits are dropped and hexadecimal, octal and binary
+// integers are converted to decimal, so the result binds to numeric fields.
+func tomlBareValue(value string) (string, error) {
+	if value == "true" || value == "false" {
+		return value, nil
+	}
+
+	number := strings.ReplaceAll(value, "_", "")
+	unsigned := strings.TrimLeft(number, "+-")
+	if len(unsigned) > 1 && unsigned[0] == '0' && strings.ContainsRune("xob", rune(unsigned[1])) {
+		intVal, err := strconv.ParseInt(number, 0, 64)
+		if err != nil {
+			return "", fmt.Errorf("invalid value %s", value)
+		}
+		return strconv.FormatInt(intVal, 10), nil
+	}
+	if unsigned == "inf" || unsigned == "nan" {
+		return number, nil
+	}
+	if _, err := strconv.ParseFloat(number, 64); err == nil && !strings.ContainsAny(unsigned, "iInN") {
+		return number, nil
+	}
+
+	// TOML allows a space instead of the T between date and time.
+	date := strings.Replace(value, " ", "T", 1)
+	for _, layout := range tomlDateLayouts {
+		if _, err := time.Parse(layout, date); err == nil {
+			return value, nil
+		}
+	}
+	return "", fmt.Errorf("invalid value %s", value)
+}
@@ -0,0 +1,81 @@
+package environment
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseTOML(t *testing.T) {
+	input := `# service config
+name = "api" # inline comment
+workers = 1_000
+
+[database]
+host = 'db.local'
+ports = [5432, 5433]
+ssl-mode = true
+tags = ["a,b", 'c']
+mask = 0xff
+started = 1979-05-27 07:32:00Z
+`
+	vars, err := parseTOML("config.toml", strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("parseTOML failed: %v", err)
+	}
+
+	expected := map[string]string{
+		"NAME":              "api",
+		"WORKERS":           "1000",
+		"DATABASE_HOST":     "db.local",
+		"DATABASE_PORTS":    "5432,5433",
+		"DATABASE_SSL_MODE": "true",
+		"DATABASE_TAGS":     "a,b,c",
+		"DATABASE_MASK":     "255",
+		"DATABASE_STARTED":  "1979-05-27 07:32:00Z",
+	}
+	if !reflect.DeepEqual(vars, expected) {
+		t.Errorf("Expected %v, got %v", expected, vars)
+	}
+
+	for _, line := range []string{"point = { x = 1 }", "greeting = hello world", "list = [1, two]", "list = [[1], [2]]"} {
+		if _, err := parseTOML("config.toml", strings.NewReader(line)); err == nil {
+			t.Errorf("Expected error for %q", line)
+		}
+	}
+}
+
+func TestLoadTOMLOverriddenByEnv(t *testing.T) {
+	type Database struct {
+		Host string `env:"HOST"`
+		Port int    `env:"PORT"`
+	}
+	type Config struct {
+		Name     string   `env:"TOML_NAME" default:"unnamed"`
+		Database Database `envPrefix:"TOML_DATABASE_"`
+	}
+
+	path := filepath.Join(t.TempDir(), "config.toml")
+	content := "toml_name = \"api\"\n\n[toml_database]\nhost = \"db.local\"\nport = 5432\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write TOML file: %v", err)
+	}
+	t.Setenv("TOML_DATABASE_HOST", "db.prod")
+
+	var cfg Config
+	if _, err := LoadWithOptions(&cfg, Options{}, path); err != nil {
+		t.Fatalf("LoadWithOptions failed: %v", err)
+	}
+
+	if cfg.Name != "api" {
+		t.Errorf("Expected name from TOML, got %q", cfg.Name)
+	}
+	if cfg.Database.Host != "db.prod" {
+		t.Errorf("Expected host from environment, got %q", cfg.Database.Host)
+	}
+	if cfg.Database.Port != 5432 {
+		t.Errorf("Expected port 5432, got %d", cfg.Database.Port)
+	}
+}
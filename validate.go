@@ -0,0 +1,443 @@
+package environment
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// patternCache holds compiled `pattern` tags keyed by their source.
+var patternCache sync.Map
+
+// checkTags reports struct-definition mistakes in the tags of typ before any
+// value is bound.
+func checkTags(typ reflect.Type, opts Options) error {
+	for i := 0; i < typ.NumField(); i++ {
+		structField := typ.Field(i)
+
+		if structField.Type.Kind() == reflect.Struct {
+			if err := checkTags(structField.Type, opts); err != nil {
+				return err
+			}
+		}
+
+		if opts.ForbidRequiredDefault && structField.Tag.Get("required") == "true" {
+			if _, ok := structField.Tag.Lookup("default"); ok || hasTagPrefix(structField.Tag, "default_") {
+				return fmt.Errorf("field %s is both required and has a default", structField.Name)
+			}
+		}
+		if unique, ok := structField.Tag.Lookup("unique"); ok && unique != "true" && unique != "error" {
+			return fmt.Errorf("field %s has invalid unique %q, want true or error", structField.Name, unique)
+		}
+	}
+	return nil
+}
+
+// hasTagPrefix reports whether tag has a key starting with prefix, such as
+// a `default_<mode>` key for the prefix "default_". It follows the
+// key:"value" syntax of reflect.StructTag.
+func hasTagPrefix(tag reflect.StructTag, prefix string) bool {
+	rest := string(tag)
+	for {
+		key, value, ok := strings.Cut(strings.TrimLeft(rest, " "), ":")
+		if !ok || key == "" || strings.ContainsAny(key, " \"") || !strings.HasPrefix(value, `"`) {
+			return false
+		}
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+
+		i := 1
+		for i < len(value) && value[i] != '"' {
+			if value[i] == '\\' {
+				i++
+			}
+			i++
+		}
+		if i >= len(value) {
+			return false
+		}
+		rest = value[i+1:]
+	}
+}
+
+// checkDuplicateNames reports two fields of typ that resolve to the same
+// variable, which is usually a copy-pasted tag. seen maps each name to the
+// path of the field reading it.
+func (b *binder) checkDuplicateNames(typ reflect.Type, prefix, path string, seen map[string]string) error {
+	for i := 0; i < typ.NumField(); i++ {
+		structField := typ.Field(i)
+		fieldPath := path + structField.Name
+
+		if structField.Type.Kind() == reflect.Struct && structField.Tag.Get("inline") != "true" && !isScalarStruct(reflect.New(structField.Type).Elem()) {
+			if err := b.checkDuplicateNames(structField.Type, b.nestedPrefix(prefix, structField), fieldPath+".", seen); err != nil {
+				return err
+			}
+			continue
+		}
+
+		name := b.envName(structField)
+		if name == "" || name == catchAllTag {
+			continue
+		}
+		name = prefix + name
+		key := name
+		if b.opts.CaseInsensitive {
+			key = strings.ToUpper(key)
+		}
+		if other, ok := seen[key]; ok {
+			return fmt.Errorf("fields %s and %s both read %s", other, fieldPath, name)
+		}
+		seen[key] = fieldPath
+	}
+	return nil
+}
+
+// checkDefault parses the default of structField into a scratch value of
+// the field's type, so that a bad default fails the load even when the
+// variable is set.
+func (b *binder) checkDefault(field reflect.Value, structField reflect.StructField) error {
+	def := b.defaultValue(structField)
+	if def == "" || strings.HasPrefix(def, "@") {
+		return nil
+	}
+	scratch := reflect.New(field.Type()).Elem()
+	if err := b.setField(scratch, structField, def); err != nil {
+		return fmt.Errorf("field %s has invalid default %q: %v", structField.Name, def, err)
+	}
+	return nil
+}
+
+// boundField is a field visited while binding, kept so that validation can
+// run once every field is set and rules may refer to fields declared later.
+type boundField struct {
+	parent reflect.Value
+	index  int
+	name   string
+	// set reports whether the field received a value, from a variable,
+	// a default or a flag file, rather than keeping its zero value.
+	set bool
+}
+
+// validateFields checks the validation tags of every bound field. It runs
+// after binding, so required_if sees the final value of its sibling.
+func (b *binder) validateFields() error {
+	for _, f := range b.bound {
+		field, structField := f.parent.Field(f.index), f.parent.Type().Field(f.index)
+		if err := validateRequiredIf(f); err != nil {
+			return err
+		}
+		if err := b.validateRequiredUnless(f); err != nil {
+			return err
+		}
+		if !f.set {
+			continue
+		}
+		if err := validateValue(field, structField); err != nil {
+			return redactSecret(structField, err)
+		}
+	}
+	return nil
+}
+
+// validateValue runs the checks that look at the value of a set field.
+func validateValue(field reflect.Value, structField reflect.StructField) error {
+	if err := validatePattern(field, structField); err != nil {
+		return err
+	}
+	if err := validatePath(field, structField); err != nil {
+		return err
+	}
+	if err := validateOneOf(field, structField); err != nil {
+		return err
+	}
+	return validateRange(field, structField)
+}
+
+// validateRange checks a numeric or time.Duration field against its `min`
+// and `max` tags. Like the other value checks it only runs on fields that
+// were set, so an optional field left at zero is not reported.
+func validateRange(field reflect.Value, structField reflect.StructField) error {
+	field = reflect.Indirect(field)
+	for _, bound := range []string{"min", "max"} {
+		limit, ok := structField.Tag.Lookup(bound)
+		if !ok || !field.IsValid() {
+			continue
+		}
+
+		scratch := reflect.New(field.Type()).Elem()
+		if err := setValue(scratch, limit); err != nil {
+			return fmt.Errorf("field %s has invalid %s %q: %v", structField.Name, bound, limit, err)
+		}
+		cmp, err := compareNumbers(field, scratch)
+		if err != nil {
+			return fmt.Errorf("field %s: %s tag %v", structField.Name, bound, err)
+		}
+		if (bound == "min" && cmp < 0) || (bound == "max" && cmp > 0) {
+			return fmt.Errorf("field %s: %v is out of range (%s %s)", structField.Name, field.Interface(), bound, limit)
+		}
+	}
+	return nil
+}
+
+// compareNumbers returns -1, 0 or 1 as a is less than, equal to or greater
+// than b, which must have the same numeric type.
+func compareNumbers(a, b reflect.Value) (int, error) {
+	switch {
+	case a.CanInt():
+		return cmp.Compare(a.Int(), b.Int()), nil
+	case a.CanUint():
+		return cmp.Compare(a.Uint(), b.Uint()), nil
+	case a.CanFloat():
+		return cmp.Compare(a.Float(), b.Float()), nil
+	}
+	return 0, fmt.Errorf("requires a numeric field, got %s", a.Type())
+}
+
+// validateRequiredUnless enforces `required_unless:"OTHER"`: the variable
+// must be set unless the sibling field OTHER, named by its env name or Go
+// name, was set.
+func (b *binder) validateRequiredUnless(f boundField) error {
+	structField := f.parent.Type().Field(f.index)
+	other := structField.Tag.Get("required_unless")
+	if other == "" || f.set {
+		return nil
+	}
+
+	for _, sibling := range b.bound {
+		if sibling.parent.Type() != f.parent.Type() || sibling.parent.UnsafeAddr() != f.parent.UnsafeAddr() {
+			continue
+		}
+		siblingField := sibling.parent.Type().Field(sibling.index)
+		if siblingField.Name != other && b.envName(siblingField) != other {
+			continue
+		}
+		if sibling.set {
+			return nil
+		}
+		if msg := structField.Tag.Get("required_msg"); msg != "" {
+			return errors.New(msg)
+		}
+		return fmt.Errorf("required environment variable %s is missing (required unless %s is set)", f.name, sibling.name)
+	}
+	return fmt.Errorf("field %s: required_unless references unknown field %s", structField.Name, other)
+}
+
+// validateOneOf checks a field tagged `oneof:"a b c"` against the listed
+// values, comparing its formatted form, so enums are matched by String().
+func validateOneOf(field reflect.Value, structField reflect.StructField) error {
+	allowed := structField.Tag.Get("oneof")
+	field = reflect.Indirect(field)
+	if allowed == "" || !field.IsValid() || !field.CanInterface() {
+		return nil
+	}
+
+	value := fmt.Sprint(field.Interface())
+	for _, candidate := range strings.Fields(allowed) {
+		if value == candidate {
+			return nil
+		}
+	}
+	return fmt.Errorf("field %s: %q is not one of %s", structField.Name, value, allowed)
+}
+
+// validateRequiredIf enforces `required_if:"Field=value"`: the variable
+// must be set when the sibling field Field holds value.
+func validateRequiredIf(f boundField) error {
+	structField := f.parent.Type().Field(f.index)
+	cond := structField.Tag.Get("required_if")
+	if cond == "" || f.set {
+		return nil
+	}
+
+	name, want, ok := strings.Cut(cond, "=")
+	if !ok {
+		return fmt.Errorf("field %s has invalid required_if %q, want Field=value", structField.Name, cond)
+	}
+	sibling := f.parent.FieldByName(name)
+	if !sibling.IsValid() || !sibling.CanInterface() {
+		return fmt.Errorf("field %s: required_if references unknown field %s", structField.Name, name)
+	}
+	if fmt.Sprint(sibling.Interface()) != want {
+		return nil
+	}
+	if msg := structField.Tag.Get("required_msg"); msg != "" {
+		return errors.New(msg)
+	}
+	return fmt.Errorf("required environment variable %s is missing (required when %s is %s)", f.name, name, want)
+}
+
+// fieldGroup collects the members of a `group` tag so that constraints
+// spanning several fields can be checked once binding is complete.
+type fieldGroup struct {
+	policy  string
+	min     string
+	members []string
+	present []string
+}
+
+func (b *binder) recordGroup(structField reflect.StructField, name string, present bool) {
+	groupName := structField.Tag.Get("group")
+	if groupName == "" {
+		return
+	}
+
+	group, ok := b.groups[groupName]
+	if !ok {
+		group = &fieldGroup{}
+		b.groups[groupName] = group
+	}
+	if policy := structField.Tag.Get("group_required"); policy != "" {
+		group.policy = policy
+	}
+	if minimum := structField.Tag.Get("group_min"); minimum != "" {
+		group.min = minimum
+	}
+	group.members = append(group.members, name)
+	if present {
+		group.present = append(group.present, name)
+	}
+}
+
+// validateGroups enforces the `group_required` policy and `group_min`
+// threshold of every group.
+func (b *binder) validateGroups() error {
+	names := make([]string, 0, len(b.groups))
+	for name := range b.groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		group := b.groups[name]
+		switch group.policy {
+		case "":
+		case "oneof":
+			if len(group.present) != 1 {
+				return fmt.Errorf("group %s requires exactly one of %s, got %d",
+					name, strings.Join(group.members, ", "), len(group.present))
+			}
+		default:
+			return fmt.Errorf("group %s has unknown group_required policy %q", name, group.policy)
+		}
+
+		if group.min != "" {
+			minimum, err := strconv.Atoi(group.min)
+			if err != nil {
+				return fmt.Errorf("group %s has invalid group_min %q", name, group.min)
+			}
+			if len(group.present) < minimum {
+				return fmt.Errorf("group %s requires at least %d of %s, got %d",
+					name, minimum, strings.Join(group.members, ", "), len(group.present))
+			}
+		}
+	}
+	return nil
+}
+
+// validatePattern checks a string field, or each element of a string
+// slice, against the regular expression in its `pattern` tag.
+func validatePattern(field reflect.Value, structField reflect.StructField) error {
+	pattern := structField.Tag.Get("pattern")
+	if pattern == "" {
+		return nil
+	}
+
+	re, err := compilePattern(pattern)
+	if err != nil {
+		return fmt.Errorf("field %s has invalid pattern %q: %v", structField.Name, pattern, err)
+	}
+
+	switch {
+	case field.Kind() == reflect.String:
+		if !re.MatchString(field.String()) {
+			return fmt.Errorf("field %s does not match pattern %q", structField.Name, pattern)
+		}
+	case field.Kind() == reflect.Slice && field.Type().Elem().Kind() == reflect.String:
+		for i := 0; i < field.Len(); i++ {
+			if !re.MatchString(field.Index(i).String()) {
+				return fmt.Errorf("field %s element %d does not match pattern %q", structField.Name, i, pattern)
+			}
+		}
+	}
+	return nil
+}
+
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	if re, ok := patternCache.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	patternCache.Store(pattern, re)
+	return re, nil
+}
+
+// validatePath checks that the path in a string field tagged
+// `path_exists:"true"` exists and, with `path_type:"file"` or "dir", that it
+// is of that kind.
+func validatePath(field reflect.Value, structField reflect.StructField) error {
+	if structField.Tag.Get("path_exists") != "true" || field.Kind() != reflect.String {
+		return nil
+	}
+
+	path := field.String()
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("field %s: path %s does not exist", structField.Name, path)
+	}
+
+	switch pathType := structField.Tag.Get("path_type"); pathType {
+	case "":
+	case "dir":
+		if !info.IsDir() {
+			return fmt.Errorf("field %s: path %s is not a directory", structField.Name, path)
+		}
+	case "file":
+		if !info.Mode().IsRegular() {
+			return fmt.Errorf("field %s: path %s is not a regular file", structField.Name, path)
+		}
+	default:
+		return fmt.Errorf("field %s has unknown path_type %q", structField.Name, pathType)
+	}
+	return nil
+}
+
+// RequireEnv checks that every named variable is set in the process
+// environment, for scripts that do not bind a struct. The error lists all
+// missing variables, one per line.
+func RequireEnv(names ...string) error {
+	var errs []error
+	for _, name := range names {
+		if _, exists := os.LookupEnv(name); exists {
+			continue
+		}
+		if osEnvFold {
+			if _, exists := lookupOSFold(name); exists {
+				continue
+			}
+		}
+		errs = append(errs, fmt.Errorf("required environment variable %s is missing", name))
+	}
+	return errors.Join(errs...)
+}
+
+// checkAdditionalRequired reports the first of Options.AdditionalRequired
+// that is not set, whatever the tags of the field that reads it say.
+func (b *binder) checkAdditionalRequired() error {
+	for _, name := range b.opts.AdditionalRequired {
+		if _, exists := b.lookup(name); !exists {
+			return fmt.Errorf("required environment variable %s is missing", name)
+		}
+	}
+	return nil
+}
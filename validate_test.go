@@ -0,0 +1,344 @@
+package environment
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheckTagsRequiredDefault(t *testing.T) {
+	type Config struct {
+		Host string `env:"HOST" required:"true" default:"localhost"`
+	}
+
+	envVars := map[string]string{"HOST": "example.com"}
+
+	var cfg Config
+	if err := parseEnv(&cfg, envVars, Options{}); err != nil {
+		t.Fatalf("parseEnv failed without the option: %v", err)
+	}
+
+	err := parseEnv(&cfg, envVars, Options{ForbidRequiredDefault: true})
+	if err == nil || !strings.Contains(err.Error(), "Host") {
+		t.Errorf("Expected error naming field Host, got %v", err)
+	}
+	var modeCfg struct {
+		Port int `env:"PORT" required:"true" default_prod:"443"`
+	}
+	err = parseEnv(&modeCfg, map[string]string{"PORT": "80"}, Options{ForbidRequiredDefault: true})
+	if err == nil || !strings.Contains(err.Error(), "Port") {
+		t.Errorf("Expected error naming field Port, got %v", err)
+	}
+}
+
+func TestValidateGroupsOneOf(t *testing.T) {
+	type Config struct {
+		URL  string `env:"DATABASE_URL" group:"db" group_required:"oneof"`
+		Host string `env:"DB_HOST" group:"db"`
+	}
+
+	tests := []struct {
+		name    string
+		envVars map[string]string
+		wantErr bool
+	}{
+		{"zero", map[string]string{}, true},
+		{"one", map[string]string{"DB_HOST": "db.local"}, false},
+		{"two", map[string]string{"DATABASE_URL": "postgres://", "DB_HOST": "db.local"}, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var cfg Config
+			err := parseEnv(&cfg, test.envVars, Options{})
+			if (err != nil) != test.wantErr {
+				t.Fatalf("Expected error %v, got %v", test.wantErr, err)
+			}
+			if err != nil && !strings.Contains(err.Error(), "group db") {
+				t.Errorf("Expected error naming group db, got %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateGroupsMin(t *testing.T) {
+	type Config struct {
+		Email string `env:"NOTIFY_EMAIL" group:"notify" group_min:"2"`
+		Slack string `env:"NOTIFY_SLACK" group:"notify"`
+		SMS   string `env:"NOTIFY_SMS" group:"notify"`
+	}
+
+	var cfg Config
+	err := parseEnv(&cfg, map[string]string{"NOTIFY_EMAIL": "ops@example.com"}, Options{})
+	if err == nil || !strings.Contains(err.Error(), "group notify") {
+		t.Errorf("Expected error naming group notify, got %v", err)
+	}
+
+	envVars := map[string]string{"NOTIFY_EMAIL": "ops@example.com", "NOTIFY_SMS": "+100"}
+	if err := parseEnv(&cfg, envVars, Options{}); err != nil {
+		t.Errorf("Expected threshold to be met, got %v", err)
+	}
+}
+
+func TestValidatePattern(t *testing.T) {
+	type Config struct {
+		Slug   string   `env:"SLUG" pattern:"^[a-z]+$"`
+		Topics []string `env:"TOPICS" pattern:"^[a-z]+$"`
+	}
+
+	var cfg Config
+	if err := parseEnv(&cfg, map[string]string{"SLUG": "orders", "TOPICS": "a,b"}, Options{}); err != nil {
+		t.Fatalf("parseEnv failed: %v", err)
+	}
+
+	err := parseEnv(&cfg, map[string]string{"SLUG": "Orders-1"}, Options{})
+	if err == nil || !strings.Contains(err.Error(), "Slug") || !strings.Contains(err.Error(), "^[a-z]+$") {
+		t.Errorf("Expected error naming Slug and the pattern, got %v", err)
+	}
+
+	err = parseEnv(&cfg, map[string]string{"TOPICS": "a,B2"}, Options{})
+	if err == nil || !strings.Contains(err.Error(), "element 1") {
+		t.Errorf("Expected error naming element 1, got %v", err)
+	}
+}
+
+func TestValidatePath(t *testing.T) {
+	type Config struct {
+		DataDir  string `env:"DATA_DIR" path_exists:"true" path_type:"dir"`
+		CertFile string `env:"CERT_FILE" path_exists:"true"`
+	}
+
+	dir := t.TempDir()
+	envVars := map[string]string{"DATA_DIR": dir, "CERT_FILE": dir}
+
+	var cfg Config
+	if err := parseEnv(&cfg, envVars, Options{}); err != nil {
+		t.Fatalf("parseEnv failed: %v", err)
+	}
+
+	missing := filepath.Join(dir, "missing.pem")
+	err := parseEnv(&cfg, map[string]string{"CERT_FILE": missing}, Options{})
+	if err == nil || !strings.Contains(err.Error(), "CertFile") {
+		t.Errorf("Expected error naming CertFile, got %v", err)
+	}
+
+	err = parseEnv(&cfg, map[string]string{"DATA_DIR": missing}, Options{})
+	if err == nil {
+		t.Error("Expected error for missing directory")
+	}
+}
+
+func TestParseEnvAdditionalRequired(t *testing.T) {
+	type Config struct {
+		APIKey string `env:"API_KEY" default:"dev-key"`
+	}
+
+	var cfg Config
+	if err := parseEnv(&cfg, map[string]string{}, Options{}); err != nil {
+		t.Fatalf("parseEnv failed: %v", err)
+	}
+
+	opts := Options{AdditionalRequired: []string{"API_KEY"}}
+	err := parseEnv(&cfg, map[string]string{}, opts)
+	if err == nil || !strings.Contains(err.Error(), "API_KEY") {
+		t.Errorf("Expected error naming API_KEY, got %v", err)
+	}
+
+	if err := parseEnv(&cfg, map[string]string{"API_KEY": "secret"}, opts); err != nil {
+		t.Errorf("Expected no error when API_KEY is set, got %v", err)
+	}
+}
+
+func TestParseEnvDuplicateNames(t *testing.T) {
+	type Database struct {
+		Host string `env:"HOST"`
+	}
+	type Config struct {
+		Host     string   `env:"DB_HOST"`
+		Database Database `envPrefix:"DB_"`
+	}
+
+	var cfg Config
+	err := parseEnv(&cfg, map[string]string{}, Options{})
+	if err == nil || !strings.Contains(err.Error(), "Host and Database.Host both read DB_HOST") {
+		t.Errorf("Expected duplicate name error, got %v", err)
+	}
+
+	type Distinct struct {
+		Host     string   `env:"HOST"`
+		Database Database `envPrefix:"DB_"`
+	}
+	var ok Distinct
+	if err := parseEnv(&ok, map[string]string{}, Options{}); err != nil {
+		t.Errorf("Expected no error for distinct names, got %v", err)
+	}
+}
+
+func TestRequireEnv(t *testing.T) {
+	t.Setenv("REQUIRE_PRESENT", "yes")
+	t.Setenv("REQUIRE_EMPTY", "")
+
+	if err := RequireEnv("REQUIRE_PRESENT", "REQUIRE_EMPTY"); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+
+	err := RequireEnv("REQUIRE_PRESENT", "REQUIRE_MISSING_A", "REQUIRE_MISSING_B")
+	if err == nil {
+		t.Fatal("Expected error for missing variables")
+	}
+	for _, name := range []string{"REQUIRE_MISSING_A", "REQUIRE_MISSING_B"} {
+		if !strings.Contains(err.Error(), name) {
+			t.Errorf("Expected error to name %s, got %v", name, err)
+		}
+	}
+	if strings.Contains(err.Error(), "REQUIRE_PRESENT") {
+		t.Errorf("Expected error not to name REQUIRE_PRESENT, got %v", err)
+	}
+}
+
+func TestParseEnvRequiredMessage(t *testing.T) {
+	type Config struct {
+		URL string `env:"DATABASE_URL" required:"true" required_msg:"Set DATABASE_URL to your Postgres connection string"`
+	}
+
+	var cfg Config
+	err := parseEnv(&cfg, map[string]string{}, Options{})
+	if err == nil || err.Error() != "Set DATABASE_URL to your Postgres connection string" {
+		t.Errorf("Expected custom message, got %v", err)
+	}
+}
+
+func TestParseEnvCheckDefaults(t *testing.T) {
+	type Config struct {
+		Port int `env:"PORT" default:"abc"`
+	}
+
+	var cfg Config
+	envVars := map[string]string{"PORT": "8080"}
+	if err := parseEnv(&cfg, envVars, Options{}); err != nil {
+		t.Fatalf("Expected bad default to go unnoticed while PORT is set, got %v", err)
+	}
+
+	err := parseEnv(&cfg, envVars, Options{CheckDefaults: true})
+	if err == nil || !strings.Contains(err.Error(), `field Port has invalid default "abc"`) {
+		t.Errorf("Expected invalid default error, got %v", err)
+	}
+}
+
+func TestParseEnvRequiredIfLaterField(t *testing.T) {
+	type Config struct {
+		CertFile string `env:"CERT_FILE" required_if:"Mode=tls"`
+		Mode     string `env:"MODE" default:"plain"`
+	}
+
+	tests := []struct {
+		name    string
+		envVars map[string]string
+		wantErr bool
+	}{
+		{"condition unmet", map[string]string{}, false},
+		{"condition met and set", map[string]string{"MODE": "tls", "CERT_FILE": "cert.pem"}, false},
+		{"condition met and missing", map[string]string{"MODE": "tls"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cfg Config
+			err := parseEnv(&cfg, tt.envVars, Options{})
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "CERT_FILE is missing (required when Mode is tls)") {
+					t.Errorf("Expected required_if error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestParseEnvRequiredUnless(t *testing.T) {
+	type Config struct {
+		Host string `env:"DB_HOST" required_unless:"DATABASE_URL"`
+		URL  string `env:"DATABASE_URL"`
+	}
+
+	tests := []struct {
+		name    string
+		envVars map[string]string
+		wantErr bool
+	}{
+		{"both set", map[string]string{"DB_HOST": "db.local", "DATABASE_URL": "postgres://db"}, false},
+		{"only url", map[string]string{"DATABASE_URL": "postgres://db"}, false},
+		{"only host", map[string]string{"DB_HOST": "db.local"}, false},
+		{"neither", map[string]string{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cfg Config
+			err := parseEnv(&cfg, tt.envVars, Options{})
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "DB_HOST is missing (required unless DATABASE_URL is set)") {
+					t.Errorf("Expected required_unless error naming both fields, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestParseEnvRangeSkipsUnset(t *testing.T) {
+	type Config struct {
+		Workers int           `env:"WORKERS" min:"1" max:"64"`
+		Ratio   float64       `env:"RATIO" max:"1"`
+		Timeout time.Duration `env:"TIMEOUT" min:"1s" default:"30s"`
+		Pin     int           `env:"PIN" max:"5" secret:"true"`
+	}
+
+	tests := []struct {
+		name    string
+		envVars map[string]string
+		wantErr string
+	}{
+		{"unset optional int", map[string]string{}, ""},
+		{"in range", map[string]string{"WORKERS": "8", "RATIO": "0.5"}, ""},
+		{"explicit zero", map[string]string{"WORKERS": "0"}, "field Workers: 0 is out of range (min 1)"},
+		{"above max", map[string]string{"RATIO": "1.5"}, "field Ratio: 1.5 is out of range (max 1)"},
+		{"duration below min", map[string]string{"TIMEOUT": "500ms"}, "field Timeout: 500ms is out of range (min 1s)"},
+		{"secret above max", map[string]string{"PIN": "31337"}, "field Pin: invalid value [REDACTED]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cfg Config
+			err := parseEnv(&cfg, tt.envVars, Options{})
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("Expected %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestParseEnvOneOfSecret(t *testing.T) {
+	type Config struct {
+		Key string `env:"API_KEY" oneof:"alpha beta" secret:"true"`
+	}
+
+	var cfg Config
+	err := parseEnv(&cfg, map[string]string{"API_KEY": "hunter2"}, Options{})
+	if err == nil || strings.Contains(err.Error(), "hunter2") || !strings.Contains(err.Error(), "[REDACTED]") {
+		t.Errorf("Expected redacted oneof error, got %v", err)
+	}
+}
@@ -0,0 +1,98 @@
+package environment
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"hash"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Watch polls paths every interval and, whenever their content changes,
+// loads a fresh T and passes it to onChange along with any load error.
+// Rewrites that leave the content identical are skipped, as the files are
+// compared by SHA-256 digest. Files pulled in with #include are watched
+// too. Watch blocks until ctx is done.
+func Watch[T any](ctx context.Context, interval time.Duration, opts Options, onChange func(cfg *T, err error), paths ...string) {
+	w := &watcher[T]{opts: opts, paths: paths}
+	_, _, _ = w.poll()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			cfg, changed, err := w.poll()
+			if changed || err != nil {
+				onChange(cfg, err)
+			}
+		}
+	}
+}
+
+type watcher[T any] struct {
+	opts   Options
+	paths  []string
+	digest []byte
+}
+
+// poll reloads the configuration if the combined digest of the watched
+// files differs from the previous poll.
+func (w *watcher[T]) poll() (*T, bool, error) {
+	digest, err := w.checksum()
+	if err != nil {
+		return nil, false, err
+	}
+	if bytes.Equal(digest, w.digest) {
+		return nil, false, nil
+	}
+	w.digest = digest
+
+	cfg := new(T)
+	if err := fillSpecification(cfg, w.opts, w.paths...); err != nil {
+		return nil, true, err
+	}
+	return cfg, true, nil
+}
+
+// checksum digests the watched files along with every file they include,
+// so that editing an included file also triggers a reload.
+func (w *watcher[T]) checksum() ([]byte, error) {
+	hash := sha256.New()
+	seen := make(map[string]bool)
+	for _, path := range w.paths {
+		if err := hashFile(hash, filepath.Clean(path), seen); err != nil {
+			return nil, err
+		}
+	}
+	return hash.Sum(nil), nil
+}
+
+func hashFile(h hash.Hash, path string, seen map[string]bool) error {
+	if seen[path] {
+		return nil
+	}
+	seen[path] = true
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	fileHash := sha256.Sum256(content)
+	h.Write(fileHash[:])
+
+	for _, line := range strings.Split(string(content), "\n") {
+		if target, ok := includeTarget(strings.TrimSpace(line)); ok {
+			if err := hashFile(h, includePath(path, target), seen); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package environment
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWatcherSkipsUnchangedContent(t *testing.T) {
+	type Config struct {
+		Value string `env:"WATCH_VALUE"`
+	}
+
+	path := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(path, []byte("WATCH_VALUE=first\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write env file: %v", err)
+	}
+
+	w := &watcher[Config]{paths: []string{path}}
+	cfg, changed, err := w.poll()
+	if err != nil || !changed {
+		t.Fatalf("Expected initial load, got changed=%v err=%v", changed, err)
+	}
+	if cfg.Value != "first" {
+		t.Errorf("Expected first, got %q", cfg.Value)
+	}
+
+	if err := os.WriteFile(path, []byte("WATCH_VALUE=first\n"), 0o600); err != nil {
+		t.Fatalf("Failed to rewrite env file: %v", err)
+	}
+	if _, changed, err := w.poll(); err != nil || changed {
+		t.Errorf("Expected no-op reload, got changed=%v err=%v", changed, err)
+	}
+
+	if err := os.WriteFile(path, []byte("WATCH_VALUE=second\n"), 0o600); err != nil {
+		t.Fatalf("Failed to rewrite env file: %v", err)
+	}
+	cfg, changed, err = w.poll()
+	if err != nil || !changed {
+		t.Fatalf("Expected reload, got changed=%v err=%v", changed, err)
+	}
+	if cfg.Value != "second" {
+		t.Errorf("Expected second, got %q", cfg.Value)
+	}
+}
+
+func TestWatcherReloadsOnIncludedFileChange(t *testing.T) {
+	type Config struct {
+		Value string `env:"WATCH_INCLUDED"`
+	}
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, ".env")
+	included := filepath.Join(dir, "shared.env")
+	if err := os.WriteFile(path, []byte("#include \"shared.env\"\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write env file: %v", err)
+	}
+	if err := os.WriteFile(included, []byte("WATCH_INCLUDED=first\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write env file: %v", err)
+	}
+
+	w := &watcher[Config]{paths: []string{path}}
+	if _, changed, err := w.poll(); err != nil || !changed {
+		t.Fatalf("Expected initial load, got changed=%v err=%v", changed, err)
+	}
+
+	if err := os.WriteFile(included, []byte("WATCH_INCLUDED=second\n"), 0o600); err != nil {
+		t.Fatalf("Failed to rewrite env file: %v", err)
+	}
+	cfg, changed, err := w.poll()
+	if err != nil || !changed {
+		t.Fatalf("Expected reload, got changed=%v err=%v", changed, err)
+	}
+	if cfg.Value != "second" {
+		t.Errorf("Expected second, got %q", cfg.Value)
+	}
+}